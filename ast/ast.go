@@ -5,6 +5,7 @@ import (
 	"MonkeyKids/token"
 	"bytes"
 	"fmt"
+	"math/big"
 	"strings"
 )
 
@@ -12,6 +13,7 @@ import (
 type Node interface {
 	TokenLiteral() string // 토큰에 대응하는 리터럴값을 반환, 디버깅과 테스트 용도로만 사용
 	String() string
+	Pos() token.Position // 노드가 시작하는 소스 위치, 에러 메시지에서 사용
 }
 
 type Statement interface {
@@ -27,6 +29,9 @@ type Expression interface {
 // Program노드 : 루트 노드
 type Program struct {
 	Statements []Statement
+	// 소스에 나온 모든 주석, 렉서가 주석을 남기도록 설정했을 때만 채워진다.
+	// 1 + /* 주석 */ 2처럼 명령문의 Doc이나 Comment가 되지 못한 주석은 여기에만 있다.
+	Comments []*CommentGroup
 }
 
 func (p *Program) TokenLiteral() string {
@@ -39,13 +44,16 @@ func (p *Program) TokenLiteral() string {
 
 // LetStatement는 필요한 필드를 모두 가진다.
 type LetStatement struct {
-	Token token.Token // 토큰
-	Name  *Identifier // 식별자
-	Value Expression  // 값을 내는 표현식 필드
+	Token   token.Token   // 토큰
+	Name    *Identifier   // 식별자
+	Value   Expression    // 값을 내는 표현식 필드
+	Doc     *CommentGroup // 명령문 바로 앞의 주석, 없으면 nil
+	Comment *CommentGroup // 명령문이 끝난 줄에서 명령문 뒤에 나온 주석, 없으면 nil
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
 
 // 노드 타입의 수를 가능한 작게 만들기 위해 사용
 // 변수 바인딩 이름을 나타내며, 선언한 이름으로 나중에 재상ㅇ
@@ -57,16 +65,20 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
 
 // return <expression>;
 type ReturnStatement struct {
 	Token       token.Token // "return" 토큰
 	ReturnValue Expression
+	Doc         *CommentGroup
+	Comment     *CommentGroup
 }
 
 func (rs *ReturnStatement) statementNode() {}
 
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
 
 // 왼쪽에서 오른쪽으로 흝어가며 토큰이 조건에 맞으면 처리하고 맞지 않으면 에러로 처리
 // 연산자 우선순위
@@ -88,11 +100,14 @@ func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
+	Doc        *CommentGroup
+	Comment    *CommentGroup
 }
 
 func (es *ExpressionStatement) statementNode() {}
 
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos }
 
 // 버퍼을 하나만들고 각 명령문의 String메서드를 호출하여 반환값을 버퍼에 쓴다
 // 그러고 나서 버퍼를 문자열로 반환
@@ -106,6 +121,13 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
@@ -148,8 +170,31 @@ type IntegerLiteral struct {
 func (il *IntegerLiteral) expressionNode() {}
 
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// int64 범위를 넘는 정수 리터럴
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (bl *BigIntegerLiteral) expressionNode()      {}
+func (bl *BigIntegerLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BigIntegerLiteral) Pos() token.Position  { return bl.Token.Pos }
+func (bl *BigIntegerLiteral) String() string       { return bl.Token.Literal }
+
+// 실수 리터럴
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type PrefixExpression struct {
 	Token    token.Token // 전위 연산자 토큰, 예) !
 	Operator string      // '='나 '!'의 문자열을 담을 필드
@@ -158,6 +203,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -180,6 +226,7 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -192,6 +239,32 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// 대입 표현식
+// <identifier> <assign operator> <expression>
+// <expression>[<expression>] <assign operator> <expression>
+// 대입 연산자는 =, +=, -=, *=, /= 이며 표현식의 값은 대입한 값이다.
+type AssignExpression struct {
+	Token    token.Token // 대입 연산자 토큰, 예) +=
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return ae.Token.Pos }
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // 불 리터럴
 type Boolean struct {
 	Token token.Token
@@ -201,8 +274,19 @@ type Boolean struct {
 func (b *Boolean) expressionNode() {}
 
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+// null 리터럴
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (nl *NullLiteral) expressionNode()      {}
+func (nl *NullLiteral) TokenLiteral() string { return nl.Token.Literal }
+func (nl *NullLiteral) Pos() token.Position  { return nl.Token.Pos }
+func (nl *NullLiteral) String() string       { return nl.Token.Literal }
+
 // if (<condition>) <consequence> else <alternative>
 // 지금까지의 성공레시피
 // AST 노드를 정의한다.
@@ -218,6 +302,7 @@ type IfExpression struct {
 func (ie *IfExpression) expressionNode() {}
 
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -233,6 +318,86 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// while (<condition>) <body>
+// 조건이 참인 동안 본문을 반복한다. 값을 만들지 않는 명령문
+type WhileStatement struct {
+	Token     token.Token // "while" 토큰
+	Condition Expression
+	Body      *BlockStatement
+	Doc       *CommentGroup
+	Comment   *CommentGroup
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// for (<키>, <값> in <반복 가능한 표현식>) <블록문>
+// 변수가 하나뿐이면 Key는 nil이다.
+type ForStatement struct {
+	Token    token.Token // "for" 토큰
+	Key      *Identifier
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+	Doc      *CommentGroup
+	Comment  *CommentGroup
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for(")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String())
+		out.WriteString(", ")
+	}
+	out.WriteString(fs.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// break; 가장 안쪽 반복문을 빠져나간다.
+type BreakStatement struct {
+	Token   token.Token // "break" 토큰
+	Doc     *CommentGroup
+	Comment *CommentGroup
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+
+// continue; 가장 안쪽 반복문의 다음 반복으로 넘어간다.
+type ContinueStatement struct {
+	Token   token.Token // "continue" 토큰
+	Doc     *CommentGroup
+	Comment *CommentGroup
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+
 type BlockStatement struct {
 	Token      token.Token // { 토큰
 	Statements []Statement
@@ -241,6 +406,7 @@ type BlockStatement struct {
 func (bs *BlockStatement) expressionNode() {}
 
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 	for _, s := range bs.Statements {
@@ -258,11 +424,13 @@ type FunctionLiteral struct {
 	Parameters []*Identifier   // 파라미터 리스트
 	Body       *BlockStatement // 함수의 몸체
 	Name       string
+	End        token.Position // 몸체를 닫는 } 의 위치, 'fn' 토큰부터 여기까지가 함수를 정의한 소스 범위
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
 
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -285,17 +453,48 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// 매크로 리터럴
+// macro(<parameters>) <blockStatement>
+// 매크로는 인수를 평가하지 않고 인용된 AST로 받아서 새 AST를 돌려준다.
+type MacroLiteral struct {
+	Token      token.Token // 'macro' 토큰
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() token.Position  { return ml.Token.Pos }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	var params []string
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+
+	return out.String()
+}
+
 // 호출 표현식
 // <expression>(<comma separated expressions>)
 type CallExpression struct {
 	Token     token.Token // 여는 괄호 토큰 '('
 	Function  Expression  // 식별자이거나 함수 리터럴
 	Arguments []Expression
+	Optional  bool // f?.(x), 함수가 null이면 인수와 체인의 나머지 고리를 평가하지 않고 null이 된다.
 }
 
 func (ce *CallExpression) expressionNode() {}
 
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Token.Pos }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -306,6 +505,9 @@ func (ce *CallExpression) String() string {
 	}
 
 	out.WriteString(ce.Function.String())
+	if ce.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
@@ -323,8 +525,33 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// 보간 문자열
+// "Hello ${name}" 는 문자열 조각과 표현식을 순서대로 담는다.
+// 컴파일러와 평가기는 각 부분을 문자열로 바꿔서 이어 붙인다.
+type InterpolatedString struct {
+	Token token.Token  // 첫 번째 TEMPLATE 토큰
+	Parts []Expression // *StringLiteral 조각 또는 보간할 표현식
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Pos() token.Position  { return is.Token.Pos }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	for _, part := range is.Parts {
+		if str, ok := part.(*StringLiteral); ok {
+			out.WriteString(str.Value)
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	return out.String()
+}
+
 // 배열 리터럴
 type ArrayLiteral struct {
 	Token    token.Token // '[' 토큰
@@ -333,6 +560,7 @@ type ArrayLiteral struct {
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -351,18 +579,23 @@ func (al *ArrayLiteral) String() string {
 // 인덱스 연산자 표현식
 // <expression>[<expression>]
 type IndexExpression struct {
-	Token token.Token // '[' 토큰
-	Left  Expression  // 접근의 대상인 객체
-	Index Expression
+	Token    token.Token // '[' 토큰
+	Left     Expression  // 접근의 대상인 객체
+	Index    Expression
+	Optional bool // h?.[k], 객체가 null이면 인덱스와 체인의 나머지 고리를 평가하지 않고 null이 된다.
 }
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -371,22 +604,72 @@ func (ie *IndexExpression) String() string {
 }
 
 // 해시
+// 키와 값은 소스에 나온 순서대로 평가해야 하므로 map이 아니라 슬라이스에 순서대로 담는다.
 type HashLiteral struct {
 	Token token.Token // "{" 토큰
-	Pairs map[Expression]Expression
+	Pairs []HashPair
+}
+
+// 해시 리터럴 안의 <키>:<값> 한 쌍
+type HashPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
 	out.WriteString("}")
 	return out.String()
 }
+
+// 주석
+// // 행 주석이나 /* */ 블록 주석 하나, 명령문도 표현식도 아니기 때문에 평가하거나 컴파일하지 않는다.
+type Comment struct {
+	Token token.Token // COMMENT 토큰, 리터럴은 주석 기호까지 포함한 원문
+}
+
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+func (c *Comment) Pos() token.Position  { return c.Token.Pos }
+func (c *Comment) String() string       { return c.Token.Literal }
+
+// 토큰 사이에 연달아 나온 주석들의 묶음
+type CommentGroup struct {
+	List []*Comment
+}
+
+func (g *CommentGroup) TokenLiteral() string { return g.List[0].TokenLiteral() }
+func (g *CommentGroup) Pos() token.Position  { return g.List[0].Pos() }
+func (g *CommentGroup) String() string {
+	var lines []string
+	for _, c := range g.List {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 주석 기호를 떼어낸 본문, 문서 도구에서 사용
+func (g *CommentGroup) Text() string {
+	var lines []string
+	for _, c := range g.List {
+		text := c.Token.Literal
+		if strings.HasPrefix(text, "//") {
+			text = text[2:]
+		} else {
+			text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		}
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,212 @@
+package ast
+
+import "math/big"
+
+// AST를 깊게 복사한다.
+// Modify는 트리를 그 자리에서 바꾸기 때문에, 함수나 매크로 몸체처럼 여러 번 쓰이는 트리를 바꾸기 전에는 먼저 복사해야 한다.
+// 주석은 바꾸지 않으므로 복사하지 않고 같이 쓴다.
+func Copy(node Node) Node {
+	switch node := node.(type) {
+	case *Program:
+		c := *node
+		c.Statements = copyStatements(node.Statements)
+		return &c
+
+	case *LetStatement:
+		c := *node
+		c.Name = copyIdentifier(node.Name)
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *ReturnStatement:
+		c := *node
+		c.ReturnValue = copyExpression(node.ReturnValue)
+		return &c
+
+	case *ExpressionStatement:
+		c := *node
+		c.Expression = copyExpression(node.Expression)
+		return &c
+
+	case *WhileStatement:
+		c := *node
+		c.Condition = copyExpression(node.Condition)
+		c.Body = copyBlock(node.Body)
+		return &c
+
+	case *ForStatement:
+		c := *node
+		c.Key = copyIdentifier(node.Key)
+		c.Value = copyIdentifier(node.Value)
+		c.Iterable = copyExpression(node.Iterable)
+		c.Body = copyBlock(node.Body)
+		return &c
+
+	case *BreakStatement:
+		c := *node
+		return &c
+
+	case *ContinueStatement:
+		c := *node
+		return &c
+
+	case *BlockStatement:
+		c := *node
+		c.Statements = copyStatements(node.Statements)
+		return &c
+
+	case *Identifier:
+		c := *node
+		return &c
+
+	case *IntegerLiteral:
+		c := *node
+		return &c
+
+	case *BigIntegerLiteral:
+		c := *node
+		c.Value = new(big.Int).Set(node.Value)
+		return &c
+
+	case *FloatLiteral:
+		c := *node
+		return &c
+
+	case *StringLiteral:
+		c := *node
+		return &c
+
+	case *Boolean:
+		c := *node
+		return &c
+
+	case *NullLiteral:
+		c := *node
+		return &c
+
+	case *PrefixExpression:
+		c := *node
+		c.Right = copyExpression(node.Right)
+		return &c
+
+	case *InfixExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Right = copyExpression(node.Right)
+		return &c
+
+	case *AssignExpression:
+		c := *node
+		c.Target = copyExpression(node.Target)
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *IfExpression:
+		c := *node
+		c.Condition = copyExpression(node.Condition)
+		c.Consequence = copyBlock(node.Consequence)
+		c.Alternative = copyBlock(node.Alternative)
+		return &c
+
+	case *FunctionLiteral:
+		c := *node
+		c.Parameters = copyIdentifiers(node.Parameters)
+		c.Body = copyBlock(node.Body)
+		return &c
+
+	case *MacroLiteral:
+		c := *node
+		c.Parameters = copyIdentifiers(node.Parameters)
+		c.Body = copyBlock(node.Body)
+		return &c
+
+	case *CallExpression:
+		c := *node
+		c.Function = copyExpression(node.Function)
+		c.Arguments = copyExpressions(node.Arguments)
+		return &c
+
+	case *InterpolatedString:
+		c := *node
+		c.Parts = copyExpressions(node.Parts)
+		return &c
+
+	case *ArrayLiteral:
+		c := *node
+		c.Elements = copyExpressions(node.Elements)
+		return &c
+
+	case *IndexExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Index = copyExpression(node.Index)
+		return &c
+
+	case *HashLiteral:
+		c := *node
+		c.Pairs = make([]HashPair, len(node.Pairs))
+		for i, pair := range node.Pairs {
+			c.Pairs[i] = HashPair{Key: copyExpression(pair.Key), Value: copyExpression(pair.Value)}
+		}
+		return &c
+	}
+	return node
+}
+
+func copyExpression(exp Expression) Expression {
+	if exp == nil {
+		return nil
+	}
+	c, _ := Copy(exp).(Expression)
+	return c
+}
+
+func copyExpressions(list []Expression) []Expression {
+	if list == nil {
+		return nil
+	}
+	c := make([]Expression, len(list))
+	for i, exp := range list {
+		c[i] = copyExpression(exp)
+	}
+	return c
+}
+
+func copyStatements(list []Statement) []Statement {
+	if list == nil {
+		return nil
+	}
+	c := make([]Statement, len(list))
+	for i, statement := range list {
+		c[i], _ = Copy(statement).(Statement)
+	}
+	return c
+}
+
+func copyIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	c := *ident
+	return &c
+}
+
+func copyIdentifiers(list []*Identifier) []*Identifier {
+	if list == nil {
+		return nil
+	}
+	c := make([]*Identifier, len(list))
+	for i, ident := range list {
+		c[i] = copyIdentifier(ident)
+	}
+	return c
+}
+
+func copyBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	c := *block
+	c.Statements = copyStatements(block.Statements)
+	return &c
+}
@@ -0,0 +1,220 @@
+package ast
+
+import "fmt"
+
+// 노드를 받아서 바꿔 넣을 노드를 돌려주는 함수
+// 바꾸지 않을 노드는 받은 그대로 돌려주면 된다.
+type ModifierFunc func(Node) Node
+
+// AST를 깊이 우선으로 순회하면서 자식 노드부터 modifier로 바꾼다.
+// 자식을 모두 바꾼 다음에 노드 자신을 modifier에 넘기고 그 결과를 돌려준다.
+// 매크로 확장과 unquote 호출을 바꿔 넣을 때 사용한다.
+//
+// 트리는 그 자리에서 바뀐다. 원래 트리를 남겨야 하면 Copy로 복사한 다음에 바꾼다.
+// 자식은 Walk와 같은 순서로 방문하고, nil인 자식은 modifier에 넘기지 않는다.
+// 명령문 목록에서 modifier가 nil을 돌려주면 그 명령문을 지운다.
+// 그 밖에 자리에 맞지 않는 노드를 돌려주면 트리가 깨지지 않도록 패닉을 일으킨다.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	// 주석
+	case *Comment:
+
+	case *CommentGroup:
+		for i, c := range node.List {
+			node.List[i] = modifyComment(c, modifier)
+		}
+
+	// 명령문
+	case *Program:
+		node.Statements = modifyStatements(node.Statements, modifier)
+
+	case *LetStatement:
+		node.Doc = modifyDoc(node.Doc, modifier)
+		node.Name = modifyIdentifier(node.Name, modifier)
+		node.Value = modifyExpression(node.Value, modifier)
+		node.Comment = modifyDoc(node.Comment, modifier)
+
+	case *ReturnStatement:
+		node.Doc = modifyDoc(node.Doc, modifier)
+		// 값 없이 return만 쓴 경우 ReturnValue는 nil이다.
+		node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		node.Comment = modifyDoc(node.Comment, modifier)
+
+	case *ExpressionStatement:
+		node.Doc = modifyDoc(node.Doc, modifier)
+		node.Expression = modifyExpression(node.Expression, modifier)
+		node.Comment = modifyDoc(node.Comment, modifier)
+
+	case *WhileStatement:
+		node.Doc = modifyDoc(node.Doc, modifier)
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+		node.Comment = modifyDoc(node.Comment, modifier)
+
+	case *ForStatement:
+		node.Doc = modifyDoc(node.Doc, modifier)
+		node.Key = modifyIdentifier(node.Key, modifier)
+		node.Value = modifyIdentifier(node.Value, modifier)
+		node.Iterable = modifyExpression(node.Iterable, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+		node.Comment = modifyDoc(node.Comment, modifier)
+
+	case *BreakStatement:
+		node.Doc = modifyDoc(node.Doc, modifier)
+		node.Comment = modifyDoc(node.Comment, modifier)
+
+	case *ContinueStatement:
+		node.Doc = modifyDoc(node.Doc, modifier)
+		node.Comment = modifyDoc(node.Comment, modifier)
+
+	case *BlockStatement:
+		node.Statements = modifyStatements(node.Statements, modifier)
+
+	// 표현식
+	case *Identifier, *IntegerLiteral, *BigIntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean, *NullLiteral:
+
+	case *PrefixExpression:
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *InfixExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *AssignExpression:
+		node.Target = modifyExpression(node.Target, modifier)
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *IfExpression:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Consequence = modifyBlock(node.Consequence, modifier)
+		node.Alternative = modifyBlock(node.Alternative, modifier)
+
+	case *FunctionLiteral:
+		node.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *MacroLiteral:
+		node.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *CallExpression:
+		node.Function = modifyExpression(node.Function, modifier)
+		node.Arguments = modifyExpressions(node.Arguments, modifier)
+
+	case *InterpolatedString:
+		node.Parts = modifyExpressions(node.Parts, modifier)
+
+	case *ArrayLiteral:
+		node.Elements = modifyExpressions(node.Elements, modifier)
+
+	case *IndexExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Index = modifyExpression(node.Index, modifier)
+
+	case *HashLiteral:
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Key = modifyExpression(pair.Key, modifier)
+			node.Pairs[i].Value = modifyExpression(pair.Value, modifier)
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Modify: unexpected node type %T", node))
+	}
+
+	return modifier(node)
+}
+
+// 바뀐 노드가 원래 자리에 들어갈 수 없으면 패닉을 일으킨다.
+func badReplacement(original, modified Node) {
+	panic(fmt.Sprintf("ast.Modify: cannot replace %T with %T", original, modified))
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	result := Modify(exp, modifier)
+	modified, ok := result.(Expression)
+	if !ok {
+		badReplacement(exp, result)
+	}
+	return modified
+}
+
+func modifyExpressions(list []Expression, modifier ModifierFunc) []Expression {
+	for i, exp := range list {
+		list[i] = modifyExpression(exp, modifier)
+	}
+	return list
+}
+
+func modifyStatements(list []Statement, modifier ModifierFunc) []Statement {
+	modified := list[:0]
+	for _, statement := range list {
+		if statement == nil {
+			continue
+		}
+		result := Modify(statement, modifier)
+		if result == nil {
+			continue
+		}
+		s, ok := result.(Statement)
+		if !ok {
+			badReplacement(statement, result)
+		}
+		modified = append(modified, s)
+	}
+	return modified
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	result := Modify(block, modifier)
+	modified, ok := result.(*BlockStatement)
+	if !ok {
+		badReplacement(block, result)
+	}
+	return modified
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	result := Modify(ident, modifier)
+	modified, ok := result.(*Identifier)
+	if !ok {
+		badReplacement(ident, result)
+	}
+	return modified
+}
+
+func modifyIdentifiers(list []*Identifier, modifier ModifierFunc) []*Identifier {
+	for i, ident := range list {
+		list[i] = modifyIdentifier(ident, modifier)
+	}
+	return list
+}
+
+func modifyDoc(doc *CommentGroup, modifier ModifierFunc) *CommentGroup {
+	if doc == nil {
+		return nil
+	}
+	result := Modify(doc, modifier)
+	modified, ok := result.(*CommentGroup)
+	if !ok {
+		badReplacement(doc, result)
+	}
+	return modified
+}
+
+func modifyComment(c *Comment, modifier ModifierFunc) *Comment {
+	result := Modify(c, modifier)
+	modified, ok := result.(*Comment)
+	if !ok {
+		badReplacement(c, result)
+	}
+	return modified
+}
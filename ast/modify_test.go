@@ -0,0 +1,320 @@
+package ast
+
+import (
+	"MonkeyKids/token"
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+		if integer.Value != 1 {
+			return node
+		}
+		integer.Value = 2
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{
+			one(),
+			two(),
+		},
+		{
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: one()},
+				},
+			},
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: two()},
+				},
+			},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&InfixExpression{Left: two(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&AssignExpression{Target: &IndexExpression{Left: one(), Index: one()}, Operator: "=", Value: one()},
+			&AssignExpression{Target: &IndexExpression{Left: two(), Index: two()}, Operator: "=", Value: two()},
+		},
+		{
+			&CallExpression{Function: one(), Arguments: []Expression{one(), two()}},
+			&CallExpression{Function: two(), Arguments: []Expression{two(), two()}},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&IfExpression{
+				Condition: two(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&WhileStatement{Condition: one(), Body: &BlockStatement{
+				Statements: []Statement{&ExpressionStatement{Expression: one()}},
+			}},
+			&WhileStatement{Condition: two(), Body: &BlockStatement{
+				Statements: []Statement{&ExpressionStatement{Expression: two()}},
+			}},
+		},
+		{
+			&ForStatement{Value: &Identifier{Value: "x"}, Iterable: one(), Body: &BlockStatement{
+				Statements: []Statement{&ExpressionStatement{Expression: one()}},
+			}},
+			&ForStatement{Value: &Identifier{Value: "x"}, Iterable: two(), Body: &BlockStatement{
+				Statements: []Statement{&ExpressionStatement{Expression: two()}},
+			}},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&ReturnStatement{},
+			&ReturnStatement{},
+		},
+		{
+			&LetStatement{Value: one()},
+			&LetStatement{Value: two()},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&InterpolatedString{Parts: []Expression{&StringLiteral{Value: "a"}, one()}},
+			&InterpolatedString{Parts: []Expression{&StringLiteral{Value: "a"}, two()}},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+	}
+
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+		}
+	}
+
+	hashLiteral := &HashLiteral{
+		Pairs: []HashPair{
+			{Key: one(), Value: one()},
+			{Key: one(), Value: one()},
+		},
+	}
+
+	Modify(hashLiteral, turnOneIntoTwo)
+
+	for _, pair := range hashLiteral.Pairs {
+		key, _ := pair.Key.(*IntegerLiteral)
+		if key.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, key.Value)
+		}
+		val, _ := pair.Value.(*IntegerLiteral)
+		if val.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, val.Value)
+		}
+	}
+}
+
+func TestModifyCoversEveryNode(t *testing.T) {
+	rename := func(node Node) Node {
+		switch node := node.(type) {
+		case *Identifier:
+			node.Value = node.Value + "_"
+		case *Comment:
+			node.Token.Literal = "# renamed"
+		}
+		return node
+	}
+
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Doc:  &CommentGroup{List: []*Comment{{}}},
+				Name: &Identifier{Value: "m"},
+				Value: &MacroLiteral{
+					Token:      token.Token{Type: token.MACRO, Literal: "macro"},
+					Parameters: []*Identifier{{Value: "x"}},
+					Body: &BlockStatement{Statements: []Statement{
+						&ExpressionStatement{Expression: &Identifier{Value: "x"}},
+					}},
+				},
+			},
+			&ForStatement{
+				Key:      &Identifier{Value: "k"},
+				Value:    &Identifier{Value: "v"},
+				Iterable: &Identifier{Value: "h"},
+				Body:     &BlockStatement{},
+				Comment:  &CommentGroup{List: []*Comment{{}}},
+			},
+		},
+	}
+
+	Modify(program, rename)
+
+	let := program.Statements[0].(*LetStatement)
+	if let.Doc.List[0].Token.Literal != "# renamed" {
+		t.Errorf("doc comment was not modified. got=%q", let.Doc.List[0].Token.Literal)
+	}
+	if let.Name.Value != "m_" {
+		t.Errorf("let name was not modified. got=%q", let.Name.Value)
+	}
+	if got := let.Value.String(); got != "macro(x_) x_" {
+		t.Errorf("macro literal was not modified. got=%q", got)
+	}
+	if got := program.Statements[1].String(); got != "for(k_, v_ in h_) " {
+		t.Errorf("for statement was not modified. got=%q", got)
+	}
+	if got := program.Statements[1].(*ForStatement).Comment.List[0].Token.Literal; got != "# renamed" {
+		t.Errorf("trailing comment was not modified. got=%q", got)
+	}
+}
+
+func TestModifyRemovesStatements(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{Expression: &IntegerLiteral{Value: 1}},
+			&BreakStatement{},
+			&ExpressionStatement{Expression: &IntegerLiteral{Value: 2}},
+		},
+	}
+
+	Modify(program, func(node Node) Node {
+		if _, ok := node.(*BreakStatement); ok {
+			return nil
+		}
+		return node
+	})
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("statement was not removed. got=%d statements", len(program.Statements))
+	}
+}
+
+func TestModifyPanicsOnBadReplacement(t *testing.T) {
+	defer func() {
+		r := recover()
+		expected := "ast.Modify: cannot replace *ast.IntegerLiteral with *ast.BreakStatement"
+		if r != expected {
+			t.Errorf("wrong panic. want=%q, got=%v", expected, r)
+		}
+	}()
+
+	Modify(&PrefixExpression{Operator: "-", Right: &IntegerLiteral{Value: 1}}, func(node Node) Node {
+		if _, ok := node.(*IntegerLiteral); ok {
+			return &BreakStatement{}
+		}
+		return node
+	})
+}
+
+func TestCopy(t *testing.T) {
+	original := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: &Identifier{Value: "f"},
+				Value: &FunctionLiteral{
+					Parameters: []*Identifier{{Value: "x"}},
+					Body: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{Expression: &InfixExpression{
+								Left:     &Identifier{Value: "x"},
+								Operator: "+",
+								Right:    &IntegerLiteral{Value: 1},
+							}},
+							&ReturnStatement{},
+						},
+					},
+				},
+			},
+			&ExpressionStatement{Expression: &HashLiteral{
+				Pairs: []HashPair{{Key: &IntegerLiteral{Value: 1}, Value: &ArrayLiteral{}}},
+			}},
+		},
+	}
+
+	copied := Copy(original)
+	if copied.String() != original.String() {
+		t.Fatalf("copy is not equal to original. got=%q, want=%q", copied.String(), original.String())
+	}
+
+	// 복사본을 바꿔도 원래 트리는 그대로여야 한다.
+	Modify(copied, func(node Node) Node {
+		if integer, ok := node.(*IntegerLiteral); ok {
+			integer.Value = 2
+		}
+		return node
+	})
+	infix := original.Statements[0].(*LetStatement).Value.(*FunctionLiteral).Body.Statements[0].(*ExpressionStatement).Expression.(*InfixExpression)
+	if infix.Right.(*IntegerLiteral).Value != 1 {
+		t.Errorf("modifying the copy changed the original")
+	}
+	for _, pair := range original.Statements[1].(*ExpressionStatement).Expression.(*HashLiteral).Pairs {
+		if pair.Key.(*IntegerLiteral).Value != 1 {
+			t.Errorf("modifying the copy changed the original hash key")
+		}
+	}
+}
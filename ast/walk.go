@@ -0,0 +1,188 @@
+package ast
+
+import "fmt"
+
+// AST 순회
+// 린터, 최적화기, 매크로 확장처럼 트리를 훑어야 하는 도구가 노드마다 타입 스위치를 직접 만들지 않도록
+// 모든 노드의 자식을 한 곳에서 정의한다.
+
+// Walk가 노드를 만날 때마다 Visit을 호출한다.
+// Visit이 돌려준 방문자 w가 nil이 아니면 Walk는 노드의 자식들을 w로 방문하고, 마지막으로 w.Visit(nil)을 호출한다.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// 깊이 우선으로 AST를 순회한다.
+// 자식은 소스에 나오는 순서대로 방문하고, 명령문 앞의 주석(Doc)은 명령문의 첫 번째 자식으로,
+// 명령문 뒤의 주석(Comment)은 마지막 자식으로 방문한다.
+// Program.Comments는 명령문의 Doc, Comment와 겹치므로 따로 방문하지 않는다.
+// 그래서 표현식 안의 주석처럼 명령문에 붙지 않은 주석은 방문하지 않는다.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// 주석
+	case *Comment:
+		// 자식이 없다.
+
+	case *CommentGroup:
+		for _, c := range n.List {
+			Walk(v, c)
+		}
+
+	// 명령문
+	case *Program:
+		walkStatements(v, n.Statements)
+
+	case *LetStatement:
+		walkDoc(v, n.Doc)
+		Walk(v, n.Name)
+		walkExpression(v, n.Value)
+		walkDoc(v, n.Comment)
+
+	case *ReturnStatement:
+		walkDoc(v, n.Doc)
+		walkExpression(v, n.ReturnValue)
+		walkDoc(v, n.Comment)
+
+	case *ExpressionStatement:
+		walkDoc(v, n.Doc)
+		walkExpression(v, n.Expression)
+		walkDoc(v, n.Comment)
+
+	case *WhileStatement:
+		walkDoc(v, n.Doc)
+		walkExpression(v, n.Condition)
+		walkBlock(v, n.Body)
+		walkDoc(v, n.Comment)
+
+	case *ForStatement:
+		walkDoc(v, n.Doc)
+		if n.Key != nil {
+			Walk(v, n.Key)
+		}
+		Walk(v, n.Value)
+		walkExpression(v, n.Iterable)
+		walkBlock(v, n.Body)
+		walkDoc(v, n.Comment)
+
+	case *BreakStatement:
+		walkDoc(v, n.Doc)
+		walkDoc(v, n.Comment)
+
+	case *ContinueStatement:
+		walkDoc(v, n.Doc)
+		walkDoc(v, n.Comment)
+
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+
+	// 표현식
+	case *Identifier, *IntegerLiteral, *BigIntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean, *NullLiteral:
+		// 자식이 없다.
+
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+
+	case *AssignExpression:
+		walkExpression(v, n.Target)
+		walkExpression(v, n.Value)
+
+	case *IfExpression:
+		walkExpression(v, n.Condition)
+		walkBlock(v, n.Consequence)
+		walkBlock(v, n.Alternative)
+
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		walkBlock(v, n.Body)
+
+	case *MacroLiteral:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		walkBlock(v, n.Body)
+
+	case *CallExpression:
+		walkExpression(v, n.Function)
+		walkExpressions(v, n.Arguments)
+
+	case *InterpolatedString:
+		walkExpressions(v, n.Parts)
+
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			walkExpression(v, pair.Key)
+			walkExpression(v, pair.Value)
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+// nil이 아닌 자식만 방문한다.
+// 값이 nil인 포인터를 인터페이스에 담으면 nil과 같지 않으므로 필드마다 따로 검사한다.
+func walkExpression(v Visitor, exp Expression) {
+	if exp != nil {
+		Walk(v, exp)
+	}
+}
+
+func walkExpressions(v Visitor, list []Expression) {
+	for _, exp := range list {
+		walkExpression(v, exp)
+	}
+}
+
+func walkStatements(v Visitor, list []Statement) {
+	for _, statement := range list {
+		if statement != nil {
+			Walk(v, statement)
+		}
+	}
+}
+
+func walkBlock(v Visitor, block *BlockStatement) {
+	if block != nil {
+		Walk(v, block)
+	}
+}
+
+func walkDoc(v Visitor, doc *CommentGroup) {
+	if doc != nil {
+		Walk(v, doc)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// 깊이 우선으로 AST를 순회하면서 노드마다 f(node)를 호출한다.
+// f가 true를 돌려주면 노드의 자식들을 계속 순회하고, 자식을 모두 순회한 뒤에 f(nil)을 호출한다.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,134 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	// # 합계
+	// for (i, x in [1, 2.5]) { while (x) { break; continue } }
+	// let f = fn(a) { return `n=${a}` } # f
+	// f?.(null) ?? g[0] = -true
+	program := &Program{
+		Statements: []Statement{
+			&ForStatement{
+				Doc:      &CommentGroup{List: []*Comment{{}}},
+				Key:      &Identifier{Value: "i"},
+				Value:    &Identifier{Value: "x"},
+				Iterable: &ArrayLiteral{Elements: []Expression{&IntegerLiteral{Value: 1}, &FloatLiteral{Value: 2.5}}},
+				Body: &BlockStatement{Statements: []Statement{
+					&WhileStatement{
+						Condition: &Identifier{Value: "x"},
+						Body: &BlockStatement{Statements: []Statement{
+							&BreakStatement{},
+							&ContinueStatement{},
+						}},
+					},
+				}},
+			},
+			&LetStatement{
+				Name: &Identifier{Value: "f"},
+				Value: &FunctionLiteral{
+					Parameters: []*Identifier{{Value: "a"}},
+					Body: &BlockStatement{Statements: []Statement{
+						&ReturnStatement{ReturnValue: &InterpolatedString{
+							Parts: []Expression{&StringLiteral{Value: "n="}, &Identifier{Value: "a"}},
+						}},
+					}},
+				},
+				Comment: &CommentGroup{List: []*Comment{{}}},
+			},
+			&ExpressionStatement{Expression: &InfixExpression{
+				Left:     &CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{&NullLiteral{}}, Optional: true},
+				Operator: "??",
+				Right: &AssignExpression{
+					Target:   &IndexExpression{Left: &Identifier{Value: "g"}, Index: &IntegerLiteral{Value: 0}},
+					Operator: "=",
+					Value:    &PrefixExpression{Operator: "-", Right: &Boolean{Value: true}},
+				},
+			}},
+		},
+	}
+
+	expected := []string{
+		"*ast.Program",
+		"*ast.ForStatement",
+		"*ast.CommentGroup", "*ast.Comment",
+		"*ast.Identifier", "*ast.Identifier",
+		"*ast.ArrayLiteral", "*ast.IntegerLiteral", "*ast.FloatLiteral",
+		"*ast.BlockStatement",
+		"*ast.WhileStatement", "*ast.Identifier",
+		"*ast.BlockStatement", "*ast.BreakStatement", "*ast.ContinueStatement",
+		"*ast.LetStatement", "*ast.Identifier",
+		"*ast.FunctionLiteral", "*ast.Identifier",
+		"*ast.BlockStatement", "*ast.ReturnStatement",
+		"*ast.InterpolatedString", "*ast.StringLiteral", "*ast.Identifier",
+		"*ast.CommentGroup", "*ast.Comment",
+		"*ast.ExpressionStatement", "*ast.InfixExpression",
+		"*ast.CallExpression", "*ast.Identifier", "*ast.NullLiteral",
+		"*ast.AssignExpression", "*ast.IndexExpression", "*ast.Identifier", "*ast.IntegerLiteral",
+		"*ast.PrefixExpression", "*ast.Boolean",
+	}
+
+	var visited []string
+	depth, maxDepth := 0, 0
+	Inspect(program, func(node Node) bool {
+		if node == nil {
+			depth--
+			return false
+		}
+		visited = append(visited, fmt.Sprintf("%T", node))
+		depth++
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		return true
+	})
+
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong visiting order.\nwant=%v\ngot =%v", expected, visited)
+	}
+	// 노드마다 f(nil)이 한 번씩 불려서 깊이가 원래대로 돌아와야 한다.
+	if depth != 0 {
+		t.Errorf("f(nil) was not called once for every node. depth=%d", depth)
+	}
+	if maxDepth != 7 {
+		t.Errorf("wrong max depth. want=7, got=%d", maxDepth)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{Expression: &MacroLiteral{
+				Parameters: []*Identifier{{Value: "x"}},
+				Body: &BlockStatement{Statements: []Statement{
+					&ExpressionStatement{Expression: &IntegerLiteral{Value: 1}},
+				}},
+			}},
+			&ExpressionStatement{Expression: &HashLiteral{
+				Pairs: []HashPair{{Key: &IntegerLiteral{Value: 2}, Value: &IfExpression{
+					Condition:   &IntegerLiteral{Value: 3},
+					Consequence: &BlockStatement{},
+				}}},
+			}},
+		},
+	}
+
+	var integers []int64
+	Inspect(program, func(node Node) bool {
+		if _, ok := node.(*MacroLiteral); ok {
+			return false
+		}
+		if integer, ok := node.(*IntegerLiteral); ok {
+			integers = append(integers, integer.Value)
+		}
+		return true
+	})
+
+	if !reflect.DeepEqual(integers, []int64{2, 3}) {
+		t.Errorf("wrong integers. want=[2 3], got=%v", integers)
+	}
+}
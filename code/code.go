@@ -1,6 +1,7 @@
 package code
 
 import (
+	"MonkeyKids/token"
 	"bytes"
 	"encoding/binary"
 	"fmt"
@@ -14,6 +15,21 @@ type Instructions []byte
 // Bytecode를 정의하지 않는 이유는 임포트 사이클 문제가 발생하기 때문에
 type Opcode byte
 
+// 명령어의 시작 오프셋과 그 명령어를 배출하게 만든 소스 위치를 연관시킨다.
+// 가상 머신에서 에러가 발생하면 이 정보로 소스의 어느 곳에서 에러가 났는지 알려준다.
+type SourceMap map[int]token.Position
+
+// 주어진 오프셋을 포함하는 명령어의 소스 위치를 찾는다.
+// 오프셋이 피연산자를 가리키고 있을 수 있으므로 명령어 시작 위치까지 거슬러 올라간다.
+func (sm SourceMap) Lookup(offset int) token.Position {
+	for i := offset; i >= 0; i-- {
+		if pos, ok := sm[i]; ok {
+			return pos
+		}
+	}
+	return token.Position{}
+}
+
 // 정수리터럴을 컴파일 중에 만나면, 평가뒤에 결과객체 *object.Integer를 추적
 // 바이트코드 명령어에서는 앞서 부여한 값으로 *object.Integer를 참조
 // 컴파일 후에,명령어를 실행할 수 있도록, 가상머신에 명령어를 전달하면, 모든 상수 표현식을 모아 저장하고 있는 자료구조인
@@ -38,6 +54,7 @@ const (
 	OpEqual
 	OpNotEqual
 	OpGreaterThan
+	OpLessThan
 	// 전위 표현식
 	// 1. 필요한 명령 코드를 정의한다.
 	// 2. 컴파일러에서 해당 명령 코드를 배출한다.
@@ -108,6 +125,40 @@ const (
 	// 컴파일러에서 자기 참조를 하는 바인딩을 탐지해서 자유 변수 심벌로 표시하고, OpGetFree 을 배출해서
 	// 표시해둔 자유변수를 스택에 올리는 게 아니라, 새로운 명령코드를 하나 배출하도록 만드는 것
 	OpCurrentClosure
+	// 문자열 보간
+	// 스택 가장 위의 값을 문자열로 바꾼다. 문자열은 그대로 두고 나머지는 Inspect 결과를 사용
+	OpToString
+	// for-in 반복문
+	// OpIter는 스택 가장 위의 값을 꺼내 반복자를 넣는다.
+	// OpIterNext는 반복자를 스택에 둔 채로 다음 요소를 넣는다. 두 번째 피연산자가 2면 키와 값을 차례로 넣는다.
+	// 다 돌았으면 반복자를 버리고 첫 번째 피연산자 위치로 점프한다.
+	OpIter
+	OpIterNext
+	// 대입
+	// OpSetFree는 스택 가장 위의 값을 꺼내 자유 변수의 셀에 저장한다.
+	// OpCaptureLocal, OpCaptureFree는 값 대신 변수를 담은 셀을 스택에 올려 OpClosure가 붙잡게 한다.
+	// 지역 변수가 아직 셀에 담겨 있지 않으면 OpCaptureLocal이 슬롯의 값을 셀로 바꾼다.
+	OpSetFree
+	OpCaptureLocal
+	OpCaptureFree
+	// 인덱스 대입
+	// OpSetIndex는 값, 인덱스, 배열이나 해시를 차례로 꺼내 그 자리에서 바꾸고 대입한 값을 다시 넣는다.
+	// OpDup은 스택 가장 위의 값 n개를 복제한다. a[i] += 1이 a와 i를 한 번만 평가하도록 한다.
+	OpSetIndex
+	OpDup
+	// 비교와 산술, 비트 연산자
+	OpLessThanOrEqual
+	OpGreaterThanOrEqual
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	// null 처리 연산자
+	// OpJumpNull은 조건을 꺼내서 Null이면 점프한다. ??와 ?.가 사용한다.
+	OpJumpNull
 )
 
 type Definition struct {
@@ -127,6 +178,7 @@ var definitions = map[Opcode]*Definition{
 	OpEqual:         {"OpEqual", []int{}},
 	OpNotEqual:      {"OpNotEqual", []int{}},
 	OpGreaterThan:   {"OpGreaterThan", []int{}},
+	OpLessThan:      {"OpLessThan", []int{}},
 	OpMinus:         {"OpMinus", []int{}},
 	OpBang:          {"OpBang", []int{}},
 	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
@@ -154,6 +206,26 @@ var definitions = map[Opcode]*Definition{
 	OpClosure:        {"OpClosure", []int{2, 1}},
 	OpGetFree:        {"OpGetFree", []int{1}},
 	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	OpToString:       {"OpToString", []int{}},
+	OpIter:           {"OpIter", []int{}},
+	OpIterNext:       {"OpIterNext", []int{2, 1}},
+	OpSetFree:        {"OpSetFree", []int{1}},
+	OpCaptureLocal:   {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:    {"OpCaptureFree", []int{1}},
+	OpSetIndex:       {"OpSetIndex", []int{}},
+	OpDup:            {"OpDup", []int{1}},
+
+	OpLessThanOrEqual:    {"OpLessThanOrEqual", []int{}},
+	OpGreaterThanOrEqual: {"OpGreaterThanOrEqual", []int{}},
+	OpMod:                {"OpMod", []int{}},
+	OpPow:                {"OpPow", []int{}},
+	OpBitAnd:             {"OpBitAnd", []int{}},
+	OpBitOr:              {"OpBitOr", []int{}},
+	OpBitXor:             {"OpBitXor", []int{}},
+	OpShiftLeft:          {"OpShiftLeft", []int{}},
+	OpShiftRight:         {"OpShiftRight", []int{}},
+
+	OpJumpNull: {"OpJumpNull", []int{2}},
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -228,6 +300,7 @@ func (ins Instructions) String() string {
 		def, err := Lookup(ins[i])
 		if err != nil {
 			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
 			continue
 		}
 		operands, read := ReadOperands(def, ins[i+1:])
@@ -40,10 +40,11 @@ func TestInstructionsString(t *testing.T) {
 		Make(OpClosure, 65535, 255),
 	}
 	expected := `0000 OpAdd
-0001 OpGetLocal 1 
-0003 OpConstant 2 
+0001 OpGetLocal 1
+0003 OpConstant 2
 0006 OpConstant 65535
-0009 OpClosure 65535 255`
+0009 OpClosure 65535 255
+`
 	concatted := Instructions{}
 	for _, ins := range instructions {
 		concatted = append(concatted, ins...)
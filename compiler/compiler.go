@@ -4,8 +4,8 @@ import (
 	"MonkeyKids/ast"
 	"MonkeyKids/code"
 	"MonkeyKids/object"
+	"MonkeyKids/token"
 	"fmt"
-	"sort"
 )
 
 // 컴파일러 : 실행 프로그램을 만들어낸다.
@@ -26,6 +26,10 @@ type Compiler struct {
 	symbolTable *SymbolTable
 	scopes      []CompilationScope
 	scopeIndex  int
+	pos         token.Position // 지금 컴파일하고 있는 노드의 소스 위치
+
+	continuesChain bool  // 지금 컴파일하는 노드가 인덱스나 호출 체인의 앞 고리인지
+	chainJumps     []int // 지금 컴파일하는 체인에서 ?.가 배출한 OpJumpNull 위치
 }
 
 /*
@@ -39,6 +43,7 @@ type Compiler struct {
 func New() *Compiler {
 	mainScope := CompilationScope{
 		instructions:        code.Instructions{},
+		sourceMap:           code.SourceMap{},
 		lastInstruction:     EmittedInstruction{},
 		previousInstruction: EmittedInstruction{},
 	}
@@ -55,6 +60,18 @@ func New() *Compiler {
 }
 
 func (c *Compiler) Compile(node ast.Node) error {
+	// 배출하는 명령어에 현재 노드의 소스 위치를 기록한다.
+	// 자식 노드를 컴파일하고 돌아오면 원래 위치로 되돌린다.
+	if pos := node.Pos(); pos.IsValid() {
+		previous := c.pos
+		c.pos = pos
+		defer func() { c.pos = previous }()
+	}
+
+	// 인덱스나 호출의 왼쪽이 아니면 새 체인이 시작된다.
+	continuesChain := c.continuesChain
+	c.continuesChain = false
+
 	switch node := node.(type) {
 
 	case *ast.Program:
@@ -73,22 +90,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.OpPop)
 
 	case *ast.InfixExpression:
-		// < 연산자를 처리할 때 , 피연산자의 순서를 변경하기 원해서
-		// if node.Operator == "<"를 가장 먼저 배치
-		// '컴파일 타임'에 비교연산 < 을 비교연산 > 로 바꾼다.
-		if node.Operator == "<" {
-			err := c.Compile(node.Right)
-			if err != nil {
-				return err
-			}
-			err = c.Compile(node.Left)
-			if err != nil {
-				return err
-			}
-			c.emit(code.OpGreaterThan)
-			return nil
+		if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
+			return c.compileLogical(node)
 		}
-
+		// 이항 연산자는 언제나 왼쪽 피연산자를 먼저 평가한다.
+		// 피연산자에 부수 효과가 있어도 평가기와 같은 순서로 실행되도록
+		// < 도 피연산자를 뒤집지 않고 OpLessThan으로 컴파일한다.
 		err := c.Compile(node.Left)
 		if err != nil {
 			return err
@@ -107,8 +114,28 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case "**":
+			c.emit(code.OpPow)
+		case "&":
+			c.emit(code.OpBitAnd)
+		case "|":
+			c.emit(code.OpBitOr)
+		case "^":
+			c.emit(code.OpBitXor)
+		case "<<":
+			c.emit(code.OpShiftLeft)
+		case ">>":
+			c.emit(code.OpShiftRight)
+		case "<":
+			c.emit(code.OpLessThan)
 		case ">":
 			c.emit(code.OpGreaterThan)
+		case "<=":
+			c.emit(code.OpLessThanOrEqual)
+		case ">=":
+			c.emit(code.OpGreaterThanOrEqual)
 		case "==":
 			c.emit(code.OpEqual)
 		case "!=":
@@ -116,13 +143,20 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		default:
 			// 컴파일 방법을 알 수 없는 중위 연산자를 만났을 때 에러를 반환하게 만든다.
-			return fmt.Errorf("unknown operator %s", node.Operator)
+			return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
 		}
 
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(integer))
 
+	case *ast.BigIntegerLiteral:
+		c.emit(code.OpConstant, c.addConstant(object.NewInteger(node.Value)))
+
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -130,6 +164,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpFalse)
 		}
 
+	case *ast.NullLiteral:
+		c.emit(code.OpNull)
+
+	// 매크로는 컴파일하기 전에 evaluator.DefineMacros와 ExpandMacros로 모두 확장해야 한다.
+	case *ast.MacroLiteral:
+		return fmt.Errorf("%s: macro literal must be bound by a top-level let statement", node.Pos())
+
 	case *ast.PrefixExpression:
 		err := c.Compile(node.Right)
 		if err != nil {
@@ -141,7 +182,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case "-":
 			c.emit(code.OpMinus)
 		default:
-			return fmt.Errorf("unknown operator %s", node.Operator)
+			return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
 
 		}
 
@@ -168,8 +209,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 
+		// 블록이 표현식으로 끝나지 않으면(let, break 등) 분기의 값은 Null
 		if c.lastInstructionIs(code.OpPop) {
 			c.removeLastPop()
+		} else {
+			c.emit(code.OpNull)
 		}
 
 		// OpJump는 조건이 참 같은 값을로 핵석됐을 때, 조건식 else 분기를 지나가야 한다.
@@ -189,11 +233,93 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 			if c.lastInstructionIs(code.OpPop) {
 				c.removeLastPop()
+			} else {
+				c.emit(code.OpNull)
 			}
 		}
 		afterAlternativePos := len(c.currentInstructions())
 		c.changedOperand(jumpPos, afterAlternativePos)
 
+	case *ast.WhileStatement:
+		// 조건 검사 위치를 기억해두고 본문 끝에서 뒤로 점프한다.
+		loop := &Loop{start: len(c.currentInstructions())}
+
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+		c.enterLoop(loop)
+		err = c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpJump, loop.start)
+		c.leaveLoop()
+
+		afterLoopPos := len(c.currentInstructions())
+		c.changedOperand(jumpNotTruthyPos, afterLoopPos)
+		for _, pos := range loop.breaks {
+			c.changedOperand(pos, afterLoopPos)
+		}
+
+	case *ast.ForStatement:
+		// 반복자는 반복문이 도는 동안 스택에 남아 있다.
+		// 다 돌면 OpIterNext가, break로 빠져나가면 break 앞의 OpPop이 반복자를 버린다.
+		err := c.Compile(node.Iterable)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpIter)
+
+		// 반복 대상을 컴파일한 다음에 정의해야 for (x in x)의 x가 바깥 x를 가리킨다.
+		value := c.symbolTable.Define(node.Value.Value)
+		count := 1
+		var key Symbol
+		if node.Key != nil {
+			key = c.symbolTable.Define(node.Key.Value)
+			count = 2
+		}
+
+		loop := &Loop{start: len(c.currentInstructions()), iterator: true}
+		iterNextPos := c.emit(code.OpIterNext, 9999, count)
+		c.storeSymbol(value)
+		if node.Key != nil {
+			c.storeSymbol(key)
+		}
+
+		c.enterLoop(loop)
+		err = c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpJump, loop.start)
+		c.leaveLoop()
+
+		afterLoopPos := len(c.currentInstructions())
+		c.replaceInstruction(iterNextPos, code.Make(code.OpIterNext, afterLoopPos, count))
+		for _, pos := range loop.breaks {
+			c.changedOperand(pos, afterLoopPos)
+		}
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: break outside loop", node.Pos())
+		}
+		if loop.iterator {
+			c.emit(code.OpPop)
+		}
+		loop.breaks = append(loop.breaks, c.emit(code.OpJump, 9999))
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: continue outside loop", node.Pos())
+		}
+		c.emit(code.OpJump, loop.start)
+
 	case *ast.BlockStatement:
 		for _, s := range node.Statements {
 			err := c.Compile(s)
@@ -212,31 +338,54 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
-		if symbol.Scope == GlobalScope {
-			c.emit(code.OpSetGlobal, symbol.Index)
-		} else {
-			c.emit(code.OpSetLocal, symbol.Index)
-		}
+		c.storeSymbol(symbol)
 
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		// 가상 머신에서는 바이트 코드를 넘기기 전에 에러를 던질 수 있다.
 		if !ok {
-			return fmt.Errorf("undefined variable %s", node.Value)
+			// quote와 unquote는 매크로를 확장할 때 평가기만 처리한다.
+			if node.Value == "quote" || node.Value == "unquote" {
+				return fmt.Errorf("%s: %s can only be used inside a macro", node.Pos(), node.Value)
+			}
+			return fmt.Errorf("%s: undefined variable %s", node.Pos(), node.Value)
 		}
 		// 환원해야 하는 심벌을 올바른 명령어로 배출할 수 있다.
 		c.loadSymbol(symbol)
 
-		if symbol.Scope == GlobalScope {
-			c.emit(code.OpGetGlobal, symbol.Index)
-		} else {
-			c.emit(code.OpGetLocal, symbol.Index)
+	case *ast.AssignExpression:
+		switch target := node.Target.(type) {
+		case *ast.Identifier:
+			err := c.compileAssign(node, target)
+			if err != nil {
+				return err
+			}
+		case *ast.IndexExpression:
+			err := c.compileIndexAssign(node, target)
+			if err != nil {
+				return err
+			}
 		}
 
 	case *ast.StringLiteral:
 		str := &object.String{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(str))
 
+	case *ast.InterpolatedString:
+		// 각 부분을 문자열로 바꿔 OpAdd로 이어 붙인다.
+		for i, part := range node.Parts {
+			err := c.Compile(part)
+			if err != nil {
+				return err
+			}
+			if _, ok := part.(*ast.StringLiteral); !ok {
+				c.emit(code.OpToString)
+			}
+			if i > 0 {
+				c.emit(code.OpAdd)
+			}
+		}
+
 	case *ast.ArrayLiteral:
 		for _, el := range node.Elements {
 			err := c.Compile(el)
@@ -247,24 +396,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.OpArray, len(node.Elements))
 
 	case *ast.HashLiteral:
-		var keys []ast.Expression
-		for k := range node.Pairs {
-			keys = append(keys, k)
-		}
-		// Go언어에서 map으로 range를 사용해서 반복하면, 순회 순서가 특정되지 않으며 다음에
-		// 다시 반복했을 때 같은 순서가 보장되지 않는다.
-		// 만약 안정된 반복순서가 필요하다면 이런 반복 순서를 명시하고
-		// 있는 별도의 자료구조를 관리해야 한다.
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-
-		for _, k := range keys {
-			err := c.Compile(k)
+		// 키와 값을 소스에 나온 순서대로 컴파일해야 {f(): 1, g(): 2}에서 f가 g보다 먼저 호출된다.
+		for _, pair := range node.Pairs {
+			err := c.Compile(pair.Key)
 			if err != nil {
 				return err
 			}
-			err = c.Compile(node.Pairs[k])
+			err = c.Compile(pair.Value)
 			if err != nil {
 				return err
 			}
@@ -272,20 +410,23 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.OpHash, len(node.Pairs)*2)
 
 	case *ast.IndexExpression:
-		err := c.Compile(node.Left)
-		if err != nil {
-			return err
-		}
-		err = c.Compile(node.Index)
+		err := c.compileChainLink(continuesChain, node.Left, node.Optional, func() error {
+			err := c.Compile(node.Index)
+			if err != nil {
+				return err
+			}
+			c.emit(code.OpIndex)
+			return nil
+		})
 		if err != nil {
 			return err
 		}
-		c.emit(code.OpIndex)
 
 	case *ast.FunctionLiteral:
 		// 함수를 컴파일할 때 배출될 명령어가 저장되는 위치를 바꾸는 것
 		c.enterScope()
-		if node.Name != "" {
+		// 본문에서 자기 이름에 대입하면 이름이 바깥 변수를 가리켜야 대입한 값이 보인다.
+		if node.Name != "" && !assignsTo(node.Body, node.Name) {
 			c.symbolTable.DefineFunctionName(node.Name)
 		}
 		// 새 스코프에 진입하고 나서 함수 몸체를 컴파일 하기전에 함수슽코프안에서 각각의파라미터를 정의한다.
@@ -307,16 +448,37 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		freeSymbols := c.symbolTable.FreeSymbols
 		numLocals := c.symbolTable.numDefinitions
+		sourceMap := c.scopes[c.scopeIndex].sourceMap
 		// leaveScope를 호출하기전 freeSymbols에 값을 넣는다.
 		instructions := c.leaveScope()
 
+		// 자유 변수를 담은 셀을 스택에 올린 다음 OpClosure가 이들을 클로저로 묶는다.
+		for _, s := range freeSymbols {
+			c.captureSymbol(s)
+		}
+
+		params := make([]string, len(node.Parameters))
+		for i, p := range node.Parameters {
+			params[i] = p.Value
+		}
+
 		compiledFn := &object.CompiledFunction{Instructions: instructions,
 			NumLocals:     numLocals,
-			NumParameters: len(node.Parameters)}
+			NumParameters: len(node.Parameters),
+			SourceMap:     sourceMap,
+			Name:          node.Name,
+			Parameters:    params,
+			Pos:           node.Pos(),
+			End:           node.End}
 		fnIndex := c.addConstant(compiledFn)
 		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
 
 	case *ast.ReturnStatement:
+		// 반환값이 없으면 Null을 반환
+		if node.ReturnValue == nil {
+			c.emit(code.OpReturn)
+			return nil
+		}
 		// 반환값 자체를 컴파일
 		err := c.Compile(node.ReturnValue)
 		if err != nil {
@@ -328,17 +490,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 		// 가상 머신이 사용할 데이터만 변화시키면 된다.
 		// 명령어와 명령어 포인터를 변경해야 한다.
 		// 만약 가상 머신 실행 중에 명령어와 명령어 포인터를 변경할 수 있다면, 함수를 실행할 수 있다.
-		err := c.Compile(node.Function)
+		err := c.compileChainLink(continuesChain, node.Function, node.Optional, func() error {
+			for _, a := range node.Arguments {
+				err := c.Compile(a)
+				if err != nil {
+					return err
+				}
+			}
+			c.emit(code.OpCall, len(node.Arguments))
+			return nil
+		})
 		if err != nil {
 			return err
 		}
-		for _, a := range node.Arguments {
-			err := c.Compile(a)
-			if err != nil {
-				return err
-			}
-		}
-		c.emit(code.OpCall, len(node.Arguments))
 
 	}
 
@@ -347,12 +511,14 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 func (c *Compiler) Bytecode() *Bytecode {
 	return &Bytecode{Instructions: c.currentInstructions(),
-		Constants: c.constants}
+		Constants: c.constants,
+		SourceMap: c.scopes[c.scopeIndex].sourceMap}
 }
 
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
+	SourceMap    code.SourceMap // 명령어 오프셋별 소스 위치
 }
 
 // 명령어를 만들고 만든 명령어를 결과에 추가한다.
@@ -360,6 +526,9 @@ type Bytecode struct {
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	ins := code.Make(op, operands...)
 	pos := c.addInstruction(ins)
+	if c.pos.IsValid() {
+		c.scopes[c.scopeIndex].sourceMap[pos] = c.pos
+	}
 
 	// 두 필드를 만들어야 한다.
 	c.setLastInstruction(op, pos)
@@ -440,13 +609,43 @@ func NewWithStates(s *SymbolTable, constants []object.Object) *Compiler {
 // 이셋을 컴파일 스코프로 엮고 컴파일 스코프 스택으로 사용한다는 의미
 type CompilationScope struct {
 	instructions        code.Instructions
+	sourceMap           code.SourceMap
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
+	loops               []*Loop // 컴파일 중인 반복문들, 가장 안쪽 반복문이 마지막
+}
+
+// 반복문 컴파일 상태
+// continue는 이미 위치를 아는 반복문 시작으로 뒤로 점프하고,
+// break는 반복문이 끝나야 목적지를 알 수 있으므로 쓰레기값으로 배출해두고 나중에 백패칭한다.
+type Loop struct {
+	start    int   // continue가 점프할 위치
+	breaks   []int // 백패칭할 break 점프 명령어들의 위치
+	iterator bool  // for-in 반복문은 스택에 반복자를 두므로 break가 먼저 버려야 한다.
+}
+
+func (c *Compiler) enterLoop(loop *Loop) {
+	c.scopes[c.scopeIndex].loops = append(c.scopes[c.scopeIndex].loops, loop)
+}
+
+func (c *Compiler) leaveLoop() {
+	loops := c.scopes[c.scopeIndex].loops
+	c.scopes[c.scopeIndex].loops = loops[:len(loops)-1]
+}
+
+// 가장 안쪽 반복문, 반복문 밖이면 nil
+func (c *Compiler) currentLoop() *Loop {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
 }
 
 func (c *Compiler) enterScope() {
 	scope := CompilationScope{
 		instructions:        code.Instructions{},
+		sourceMap:           code.SourceMap{},
 		lastInstruction:     EmittedInstruction{},
 		previousInstruction: EmittedInstruction{},
 	}
@@ -482,6 +681,186 @@ func (c *Compiler) replaceLastPopWithReturn() {
 	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
 }
 
+func (c *Compiler) compileAssign(node *ast.AssignExpression, ident *ast.Identifier) error {
+	symbol, ok := c.symbolTable.Resolve(ident.Value)
+	if !ok {
+		return fmt.Errorf("%s: undefined variable %s", ident.Pos(), ident.Value)
+	}
+	if symbol.Scope == BuiltinScope {
+		return fmt.Errorf("%s: cannot assign to %s", node.Pos(), ident.Value)
+	}
+
+	// x += y는 x = x + y와 같다.
+	if op, ok := compoundAssignOps[node.Operator]; ok {
+		c.loadSymbol(symbol)
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(op)
+	} else {
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+	}
+	// 대입 표현식의 값은 대입한 값이다.
+	c.storeSymbol(symbol)
+	c.loadSymbol(symbol)
+	return nil
+}
+
+// node 안에 name에 대입하는 표현식이 있는지 확인한다.
+func assignsTo(node ast.Node, name string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if assign, ok := n.(*ast.AssignExpression); ok {
+			if ident, ok := assign.Target.(*ast.Identifier); ok && ident.Value == name {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// 배열이나 해시, 인덱스, 값을 차례로 스택에 올리고 OpSetIndex를 배출한다.
+func (c *Compiler) compileIndexAssign(node *ast.AssignExpression, target *ast.IndexExpression) error {
+	err := c.Compile(target.Left)
+	if err != nil {
+		return err
+	}
+	err = c.Compile(target.Index)
+	if err != nil {
+		return err
+	}
+
+	if op, ok := compoundAssignOps[node.Operator]; ok {
+		// 배열과 인덱스를 복제해 두고 현재 값을 읽는다.
+		c.emit(code.OpDup, 2)
+		c.emit(code.OpIndex)
+		err = c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(op)
+	} else {
+		err = c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.emit(code.OpSetIndex)
+	return nil
+}
+
+// &&, ||, ??는 왼쪽 피연산자로 결과가 정해지면 오른쪽을 평가하지 않고 왼쪽 값을 그대로 남긴다.
+// OpJumpNotTruthy와 OpJumpNull이 조건을 꺼내므로 왼쪽 값을 복제해서 검사한다.
+//
+//	a && b: a, OpDup 1, OpJumpNotTruthy end, OpPop, b, end:
+//	a || b: a, OpDup 1, OpJumpNotTruthy right, OpJump end, right: OpPop, b, end:
+//	a ?? b: a, OpDup 1, OpJumpNull right, OpJump end, right: OpPop, b, end:
+func (c *Compiler) compileLogical(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpDup, 1)
+	condition := code.OpJumpNotTruthy
+	if node.Operator == "??" {
+		condition = code.OpJumpNull
+	}
+	conditionalJumpPos := c.emit(condition, 9999)
+
+	var jumpPos int
+	if node.Operator != "&&" {
+		jumpPos = c.emit(code.OpJump, 9999)
+		c.changedOperand(conditionalJumpPos, len(c.currentInstructions()))
+	}
+
+	c.emit(code.OpPop)
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+
+	afterRightPos := len(c.currentInstructions())
+	if node.Operator != "&&" {
+		c.changedOperand(jumpPos, afterRightPos)
+	} else {
+		c.changedOperand(conditionalJumpPos, afterRightPos)
+	}
+	return nil
+}
+
+// h?.[a][b](c)처럼 인덱스와 호출이 이어진 체인에서 ?.가 null을 만나면 체인 끝까지 건너뛰고 null을 그대로 남긴다.
+// left는 체인의 앞 고리로 컴파일하고, 체인의 가장 바깥 고리가 모든 ?.의 점프를 자기 끝으로 고친다.
+// 인덱스와 인수는 새 체인이라서 그 안의 ?.는 바깥 체인을 건너뛰지 않는다.
+//
+//	h?.[a][b]: h, OpDup 1, OpJumpNull end, a, OpIndex, b, OpIndex, end:
+func (c *Compiler) compileChainLink(continuesChain bool, left ast.Expression, optional bool, compileRest func() error) error {
+	outer := c.chainJumps
+	if !continuesChain {
+		c.chainJumps = nil
+	}
+
+	c.continuesChain = true
+	err := c.Compile(left)
+	if err != nil {
+		return err
+	}
+	if optional {
+		c.emit(code.OpDup, 1)
+		c.chainJumps = append(c.chainJumps, c.emit(code.OpJumpNull, 9999))
+	}
+	err = compileRest()
+	if err != nil {
+		return err
+	}
+
+	if !continuesChain {
+		for _, pos := range c.chainJumps {
+			c.changedOperand(pos, len(c.currentInstructions()))
+		}
+		c.chainJumps = outer
+	}
+	return nil
+}
+
+// 복합 대입 연산자가 값을 계산할 때 쓰는 명령 코드
+var compoundAssignOps = map[string]code.Opcode{
+	"+=": code.OpAdd,
+	"-=": code.OpSub,
+	"*=": code.OpMul,
+	"/=": code.OpDiv,
+}
+
+// 스택 가장 위의 값을 꺼내 심벌에 바인딩한다.
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpSetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+// 클로저가 자유 변수를 붙잡을 수 있도록 변수의 셀을 스택에 올린다.
+// 자기 자신을 가리키는 함수 이름은 본문에서 대입하지 않으므로 값을 그대로 올린다.
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpCaptureLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpCaptureFree, s.Index)
+	default:
+		c.loadSymbol(s)
+	}
+}
+
 func (c *Compiler) loadSymbol(s Symbol) {
 	switch s.Scope {
 	case GlobalScope:
@@ -81,6 +81,38 @@ func TestIntegerArithmetic(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	operators := []struct {
+		operator string
+		opcode   code.Opcode
+	}{
+		{"%", code.OpMod},
+		{"**", code.OpPow},
+		{"&", code.OpBitAnd},
+		{"|", code.OpBitOr},
+		{"^", code.OpBitXor},
+		{"<<", code.OpShiftLeft},
+		{">>", code.OpShiftRight},
+		{"<=", code.OpLessThanOrEqual},
+		{">=", code.OpGreaterThanOrEqual},
+	}
+
+	tests := []compilerTestCase{}
+	for _, op := range operators {
+		tests = append(tests, compilerTestCase{
+			input:             "2 " + op.operator + " 1",
+			expectedConstants: []interface{}{2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(op.opcode),
+				code.Make(code.OpPop),
+			},
+		})
+	}
+	runCompilerTests(t, tests)
+}
+
 func runCompilerTests(t *testing.T, tests []compilerTestCase) {
 	t.Helper() // 특정 테스트 함수를 테스트 도움 함수로 인식
 
@@ -150,6 +182,12 @@ func testConstants(t *testing.T,
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s", i, err)
 			}
 
+		case float64:
+			result, ok := actual[i].(*object.Float)
+			if !ok || result.Value != constant {
+				return fmt.Errorf("constant %d - not Float %g. got=%T (%+v)", i, constant, actual[i], actual[i])
+			}
+
 		case string:
 			err := testStringObject(constant, actual[i])
 			if err != nil {
@@ -183,6 +221,22 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+func TestFloatArithmetic(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1.5 + 2",
+			expectedConstants: []interface{}{1.5, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -213,11 +267,11 @@ func TestBooleanExpression(t *testing.T) {
 		},
 		{
 			input:             "1 < 2",
-			expectedConstants: []interface{}{2, 1},
+			expectedConstants: []interface{}{1, 2},
 			expectedInstructions: []code.Instructions{
 				code.Make(code.OpConstant, 0),
 				code.Make(code.OpConstant, 1),
-				code.Make(code.OpGreaterThan),
+				code.Make(code.OpLessThan),
 				code.Make(code.OpPop),
 			},
 		},
@@ -346,6 +400,336 @@ func TestConditionals(t *testing.T) {
 				// 0015
 				code.Make(code.OpPop),
 			}},
+		{
+			// 표현식으로 끝나지 않는 분기는 Null을 남긴다.
+			input:             `if (true) { let x = 1; }`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 14),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpSetGlobal, 0),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpJump, 15),
+				// 0014
+				code.Make(code.OpNull),
+				// 0015
+				code.Make(code.OpPop),
+			}},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `while (true) { 1 }`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 11),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpPop),
+				// 0008
+				// 조건 검사로 되돌아간다.
+				code.Make(code.OpJump, 0),
+			}},
+		{
+			input:             `while (true) { if (false) { continue; } break; }`,
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 23),
+				// 0004
+				code.Make(code.OpFalse),
+				// 0005
+				code.Make(code.OpJumpNotTruthy, 15),
+				// 0008
+				code.Make(code.OpJump, 0), // continue
+				// 0011
+				code.Make(code.OpNull),
+				// 0012
+				code.Make(code.OpJump, 16),
+				// 0015
+				code.Make(code.OpNull),
+				// 0016
+				code.Make(code.OpPop),
+				// 0017
+				code.Make(code.OpJump, 23), // break
+				// 0020
+				code.Make(code.OpJump, 0),
+			}},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `1 && 2`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpDup, 1),
+				// 0005
+				// 거짓이면 복제하지 않은 왼쪽 값을 남기고 끝으로 점프한다.
+				code.Make(code.OpJumpNotTruthy, 12),
+				// 0008
+				code.Make(code.OpPop),
+				// 0009
+				code.Make(code.OpConstant, 1),
+				// 0012
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `1 || 2`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpDup, 1),
+				// 0005
+				code.Make(code.OpJumpNotTruthy, 11),
+				// 0008
+				// 참이면 왼쪽 값을 남기고 끝으로 점프한다.
+				code.Make(code.OpJump, 15),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 1),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestNullOperators(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `null`,
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `1 ?? 2`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpDup, 1),
+				// 0005
+				code.Make(code.OpJumpNull, 11),
+				// 0008
+				// null이 아니면 왼쪽 값을 남기고 끝으로 점프한다.
+				code.Make(code.OpJump, 15),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 1),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `[1]?.[0]`,
+			expectedConstants: []interface{}{1, 0},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpDup, 1),
+				// 0008
+				// null이면 인덱스를 평가하지 않고 null을 남긴다.
+				code.Make(code.OpJumpNull, 15),
+				// 0011
+				code.Make(code.OpConstant, 1),
+				// 0014
+				code.Make(code.OpIndex),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `[1]?.[0][1]`,
+			expectedConstants: []interface{}{1, 0, 1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpDup, 1),
+				// 0008
+				// null이면 체인의 나머지 고리까지 건너뛴다.
+				code.Make(code.OpJumpNull, 19),
+				// 0011
+				code.Make(code.OpConstant, 1),
+				// 0014
+				code.Make(code.OpIndex),
+				// 0015
+				code.Make(code.OpConstant, 2),
+				// 0018
+				code.Make(code.OpIndex),
+				// 0019
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `len?.(1)`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpGetBuiltin, 0),
+				// 0002
+				code.Make(code.OpDup, 1),
+				// 0004
+				code.Make(code.OpJumpNull, 12),
+				// 0007
+				code.Make(code.OpConstant, 0),
+				// 0010
+				code.Make(code.OpCall, 1),
+				// 0012
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `for (x in [1, 2]) { x; break; }`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpConstant, 1),
+				// 0006
+				code.Make(code.OpArray, 2),
+				// 0009
+				code.Make(code.OpIter),
+				// 0010
+				code.Make(code.OpIterNext, 28, 1),
+				// 0014
+				code.Make(code.OpSetGlobal, 0),
+				// 0017
+				code.Make(code.OpGetGlobal, 0),
+				// 0020
+				code.Make(code.OpPop),
+				// 0021
+				// break는 반복자를 버리고 빠져나간다.
+				code.Make(code.OpPop),
+				// 0022
+				code.Make(code.OpJump, 28),
+				// 0025
+				code.Make(code.OpJump, 10),
+			}},
+		{
+			input:             `for (i, ch in "ab") { i }`,
+			expectedConstants: []interface{}{"ab"},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpIter),
+				// 0004
+				code.Make(code.OpIterNext, 21, 2),
+				// 0008
+				// 값이 스택 가장 위에 있으므로 먼저 바인딩한다.
+				code.Make(code.OpSetGlobal, 0),
+				// 0011
+				code.Make(code.OpSetGlobal, 1),
+				// 0014
+				code.Make(code.OpGetGlobal, 1),
+				// 0017
+				code.Make(code.OpPop),
+				// 0018
+				code.Make(code.OpJump, 4),
+			}},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `let x = 1; x = 2;`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				// 대입 표현식의 값
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { let x = 1; x -= 2 }`,
+			expectedConstants: []interface{}{1, 2, []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetLocal, 0),
+				code.Make(code.OpGetLocal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSub),
+				code.Make(code.OpSetLocal, 0),
+				code.Make(code.OpGetLocal, 0),
+				code.Make(code.OpReturnValue),
+			}},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { let a = 1; fn() { a += 2 } }`,
+			expectedConstants: []interface{}{1, 2, []code.Instructions{
+				code.Make(code.OpGetFree, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetFree, 0),
+				code.Make(code.OpGetFree, 0),
+				code.Make(code.OpReturnValue),
+			}, []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetLocal, 0),
+				// 값이 아니라 a를 담은 셀을 붙잡는다.
+				code.Make(code.OpCaptureLocal, 0),
+				code.Make(code.OpClosure, 2, 1),
+				code.Make(code.OpReturnValue),
+			}},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpPop),
+			},
+		},
 	}
 	runCompilerTests(t, tests)
 }
@@ -397,14 +781,14 @@ func TestGlobalLetStatements(t *testing.T) {
 func TestStringExpression(t *testing.T) {
 	tests := []compilerTestCase{
 		{
-			input:             "monkey",
+			input:             `"monkey"`,
 			expectedConstants: []interface{}{"monkey"},
 			expectedInstructions: []code.Instructions{
 				code.Make(code.OpConstant, 0),
 				code.Make(code.OpPop),
 			},
 		}, {
-			input:             "mon" + "key",
+			input:             `"mon" + "key"`,
 			expectedConstants: []interface{}{"mon", "key"},
 			expectedInstructions: []code.Instructions{
 				code.Make(code.OpConstant, 0),
@@ -412,6 +796,26 @@ func TestStringExpression(t *testing.T) {
 				code.Make(code.OpAdd),
 				code.Make(code.OpPop),
 			},
+		}, {
+			input:             `"a${1}b"`,
+			expectedConstants: []interface{}{"a", 1, "b"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpToString),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		}, {
+			input:             `"${1}"`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpToString),
+				code.Make(code.OpPop),
+			},
 		},
 	}
 	runCompilerTests(t, tests)
@@ -495,7 +899,7 @@ func TestHashLiteral(t *testing.T) {
 			},
 		},
 		{
-			input:             "{1:2 + 3. 4: 5 * 6}",
+			input:             "{1: 2 + 3, 4: 5 * 6}",
 			expectedConstants: []interface{}{1, 2, 3, 4, 5, 6},
 			expectedInstructions: []code.Instructions{
 				code.Make(code.OpConstant, 0),
@@ -510,6 +914,19 @@ func TestHashLiteral(t *testing.T) {
 				code.Make(code.OpPop),
 			},
 		},
+		{
+			// 키를 정렬하지 않고 소스에 나온 순서대로 컴파일한다.
+			input:             `{"b": 1, "a": 2}`,
+			expectedConstants: []interface{}{"b", 1, "a", 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpHash, 4),
+				code.Make(code.OpPop),
+			},
+		},
 	}
 	runCompilerTests(t, tests)
 }
@@ -551,6 +968,43 @@ func TestIndexExpression(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestIndexAssignExpression(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let a = [1]; a[0] = 2",
+			expectedConstants: []interface{}{1, 0, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpSetIndex),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "let h = {}; h[1] *= 3",
+			expectedConstants: []interface{}{1, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpHash, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 0),
+				// 해시와 인덱스를 한 번만 평가하고 복제해서 현재 값을 읽는다.
+				code.Make(code.OpDup, 2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMul),
+				code.Make(code.OpSetIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 /*
 object.CompiledFunction: 컴파일된 함수에 포함된 명령어를 담을 구조체. 컴파일러에서는 이 구조체를 상수 OpConstant 피연산자를 통해 상수로 가상머신을 넘긴다.
 code.OpCall: 가상머신 스택의 가장 위에 있는 *object.CompiledFunction을 실행
@@ -732,7 +1186,7 @@ func TestFunctionCalls(t *testing.T) {
 				code.Make(code.OpCall, 1),
 				code.Make(code.OpPop),
 			}},
-		{input: `let manyArg = fn(a, b, c) { }; noArg(24, 25, 26);`,
+		{input: `let manyArg = fn(a, b, c) { }; manyArg(24, 25, 26);`,
 			expectedConstants: []interface{}{
 				[]code.Instructions{
 					code.Make(code.OpReturn),
@@ -765,7 +1219,7 @@ func TestFunctionCalls(t *testing.T) {
 				code.Make(code.OpCall, 1),
 				code.Make(code.OpPop),
 			}},
-		{input: `let manyArg = fn(a, b, c) { a; b; c; }; noArg(24, 25, 26);`,
+		{input: `let manyArg = fn(a, b, c) { a; b; c; }; manyArg(24, 25, 26);`,
 			expectedConstants: []interface{}{
 				[]code.Instructions{
 					code.Make(code.OpGetLocal, 0),
@@ -809,7 +1263,7 @@ func TestLetStatementScopes(t *testing.T) {
 			},
 		},
 		{
-			input: `let num = 55; num`,
+			input: `fn() { let num = 55; num }`,
 			expectedConstants: []interface{}{
 				55,
 				[]code.Instructions{
@@ -890,7 +1344,7 @@ func TestClosure(t *testing.T) {
 			code.Make(code.OpAdd),
 			code.Make(code.OpReturnValue),
 		}, []code.Instructions{
-			code.Make(code.OpGetLocal, 0),
+			code.Make(code.OpCaptureLocal, 0),
 			code.Make(code.OpClosure, 0, 1),
 			code.Make(code.OpReturnValue),
 		}},
@@ -907,12 +1361,12 @@ func TestClosure(t *testing.T) {
 			code.Make(code.OpAdd),
 			code.Make(code.OpReturnValue),
 		}, []code.Instructions{
-			code.Make(code.OpGetFree, 0),
-			code.Make(code.OpGetLocal, 0),
+			code.Make(code.OpCaptureFree, 0),
+			code.Make(code.OpCaptureLocal, 0),
 			code.Make(code.OpClosure, 0, 2),
 			code.Make(code.OpReturnValue),
 		}, []code.Instructions{
-			code.Make(code.OpGetLocal, 0),
+			code.Make(code.OpCaptureLocal, 0),
 			code.Make(code.OpClosure, 1, 1),
 			code.Make(code.OpReturnValue),
 		}},
@@ -928,7 +1382,7 @@ func TestClosure(t *testing.T) {
 				[]code.Instructions{
 					code.Make(code.OpConstant, 3),
 					code.Make(code.OpSetLocal, 0),
-					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpGetGlobal, 0),
 					code.Make(code.OpGetFree, 0),
 					code.Make(code.OpAdd),
 					code.Make(code.OpGetFree, 1),
@@ -939,14 +1393,14 @@ func TestClosure(t *testing.T) {
 				}, []code.Instructions{
 					code.Make(code.OpConstant, 2),
 					code.Make(code.OpSetLocal, 0),
-					code.Make(code.OpGetFree, 0),
-					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCaptureFree, 0),
+					code.Make(code.OpCaptureLocal, 0),
 					code.Make(code.OpClosure, 4, 2),
 					code.Make(code.OpReturnValue),
 				}, []code.Instructions{
 					code.Make(code.OpConstant, 1),
 					code.Make(code.OpSetLocal, 0),
-					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCaptureLocal, 0),
 					code.Make(code.OpClosure, 5, 1),
 					code.Make(code.OpReturnValue),
 				}},
@@ -960,3 +1414,58 @@ func TestClosure(t *testing.T) {
 	}
 	runCompilerTests(t, tests)
 }
+
+func TestCompilerErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1;\nx + y", "2:5: undefined variable y"},
+		{"fn() {\n  fn() { z }\n}", "2:10: undefined variable z"},
+		{"y = 1", "1:1: undefined variable y"},
+		{"len += 1", "1:5: cannot assign to len"},
+		{"quote(1 + 2)", "1:1: quote can only be used inside a macro"},
+		{"let f = fn() { macro(x) { x } }", "1:16: macro literal must be bound by a top-level let statement"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		compiler := New()
+		err := compiler.Compile(program)
+		if err == nil {
+			t.Fatalf("expected compiler error but resulted in none.")
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	input := "1;\n  2 + 3"
+
+	program := parse(input)
+	compiler := New()
+	err := compiler.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := compiler.Bytecode()
+
+	tests := []struct {
+		offset   int
+		expected string
+	}{
+		{0, "1:1"},  // OpConstant 0
+		{3, "1:1"},  // OpPop
+		{4, "2:3"},  // OpConstant 1
+		{7, "2:7"},  // OpConstant 2
+		{10, "2:5"}, // OpAdd
+		{11, "2:3"}, // OpPop
+	}
+	for _, tt := range tests {
+		pos := bytecode.SourceMap.Lookup(tt.offset)
+		if pos.String() != tt.expected {
+			t.Errorf("wrong position at %d. want=%s, got=%s", tt.offset, tt.expected, pos)
+		}
+	}
+}
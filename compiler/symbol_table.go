@@ -39,7 +39,13 @@ func NewSymbolTable() *SymbolTable {
 	return &SymbolTable{store: s, FreeSymbols: free}
 }
 
+// 같은 스코프에서 이미 정의한 이름을 다시 정의하면 새 슬롯을 만들지 않고 기존 심벌을 돌려준다.
+// let x = 1; let x = 2;의 두 x는 같은 바인딩이므로 x를 붙잡은 클로저도 바뀐 값을 본다.
 func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+
 	symbol := Symbol{
 		Name:  name,
 		Index: s.numDefinitions}
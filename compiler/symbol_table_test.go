@@ -66,7 +66,7 @@ func TestResolveLocal(t *testing.T) {
 		},
 	}
 	for _, sym := range expected {
-		result, ok := global.Resolve(sym.Name)
+		result, ok := local.Resolve(sym.Name)
 		if !ok {
 			t.Errorf("name %s not resolvable", sym.Name)
 			continue
@@ -113,7 +113,7 @@ func TestResolveNestedLocal(t *testing.T) {
 	}
 	for _, tt := range tests {
 		for _, sym := range tt.expectedSymbols {
-			result, ok := global.Resolve(sym.Name)
+			result, ok := tt.table.Resolve(sym.Name)
 			if !ok {
 				t.Errorf("name %s not resolvable", sym.Name)
 				continue
@@ -171,6 +171,23 @@ func TestDefine(t *testing.T) {
 		t.Errorf("expected f=%+v, got=%+v", expected["f"], f)
 	}
 }
+func TestRedefine(t *testing.T) {
+	global := NewSymbolTable()
+	a := global.Define("a")
+	global.Define("b")
+	if again := global.Define("a"); again != a {
+		t.Errorf("redefining a should reuse %+v, got=%+v", a, again)
+	}
+
+	local := NewEnclosedSymbolTable(global)
+	if shadow := local.Define("a"); shadow != (Symbol{Name: "a", Scope: LocalScope, Index: 0}) {
+		t.Errorf("a in local scope should be a new local, got=%+v", shadow)
+	}
+	if again := local.Define("a"); again.Index != 0 || local.numDefinitions != 1 {
+		t.Errorf("redefining local a should reuse index 0, got=%+v (%d definitions)", again, local.numDefinitions)
+	}
+}
+
 func TestDefineResolveBuiltins(t *testing.T) {
 	global := NewSymbolTable()
 	firstLocal := NewEnclosedSymbolTable(global)
@@ -242,7 +259,7 @@ func TestResolveFree(t *testing.T) {
 	}
 	for _, tt := range tests {
 		for _, sym := range tt.expectedSymbols {
-			result, ok := global.Resolve(sym.Name)
+			result, ok := tt.table.Resolve(sym.Name)
 			if !ok {
 				t.Errorf("name %s not resolvable", sym.Name)
 				continue
@@ -9,10 +9,15 @@ import (
 // 호출 인수를 스텍에 넣는다.
 // OpCall 명령어로 함수를 호출한다.
 var builtins = map[string]*object.Builtin{
-	"len":   object.GetBuiltinByName("len"),
-	"puts":  object.GetBuiltinByName("puts"),
-	"first": object.GetBuiltinByName("first"),
-	"last":  object.GetBuiltinByName("last"),
-	"rest":  object.GetBuiltinByName("rest"),
-	"push":  object.GetBuiltinByName("push"),
+	"len":    object.GetBuiltinByName("len"),
+	"puts":   object.GetBuiltinByName("puts"),
+	"first":  object.GetBuiltinByName("first"),
+	"last":   object.GetBuiltinByName("last"),
+	"rest":   object.GetBuiltinByName("rest"),
+	"push":   object.GetBuiltinByName("push"),
+	"int":    object.GetBuiltinByName("int"),
+	"float":  object.GetBuiltinByName("float"),
+	"range":  object.GetBuiltinByName("range"),
+	"keys":   object.GetBuiltinByName("keys"),
+	"values": object.GetBuiltinByName("values"),
 }
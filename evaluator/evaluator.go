@@ -18,7 +18,11 @@ package evaluator
 import (
 	"MonkeyKids/ast"
 	"MonkeyKids/object"
+	"bytes"
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 )
 
 // true나 false를 만날때마다 object.Boolean을 다시 만들어야 하는가??
@@ -27,6 +31,9 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 // 자체평가 표현식
@@ -36,7 +43,14 @@ var (
 // env *object.Environment
 // 환경은 인터프리터가 값을 추적할 때 사용하는 객체로, 값을 이름과연관시킨다.
 // 그저 문자열과 객체를 연관시키는 해시 맵에 불과
-func Eval(node ast.Node, env *object.Environment) object.Object {
+func Eval(node ast.Node, env *object.Environment) (result object.Object) {
+	// 위치가 없는 에러에는 에러를 만든 가장 안쪽 노드의 위치를 붙인다.
+	defer func() {
+		if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+			err.Pos = node.Pos()
+		}
+	}()
+
 	switch node := node.(type) {
 	// 명령문
 	case *ast.Program:
@@ -48,6 +62,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
+	case *ast.NullLiteral:
+		return NULL
+
+	// 매크로는 DefineMacros가 미리 꺼내 가므로 여기까지 오면 최상위 let이 아닌 곳에 쓴 것이다.
+	case *ast.MacroLiteral:
+		return newError("macro literal must be bound by a top-level let statement")
+
 	case *ast.BlockStatement:
 		return evalBlockStatements(node, env)
 
@@ -55,6 +76,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalIfExpression(node, env)
 
 	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			return &object.ReturnValue{Value: NULL}
+		}
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
@@ -69,6 +93,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
+			return evalLogicalExpression(node, env)
+		}
+		// 이항 연산자는 언제나 왼쪽 피연산자를 먼저 평가한다. 가상 머신도 같은 순서를 따른다.
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -82,34 +110,45 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isError(val) || isLoopControl(val) {
 			return val
 		}
 		env.Set(node.Name.Value, val)
 
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Env: env, Body: body,
+			Name: node.Name, Pos: node.Pos(), End: node.End}
 
 		// 인수를 평가하는 동작은 표현식 리스트를 평가하는 동작과 다를바 없다.
 		// 표현식
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-		return applyFunction(function, args)
+		result, _ := evalChain(node, env)
+		return result
 
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -118,16 +157,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Array{Elements: elements}
 
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
-		}
-		index := Eval(node.Index, env)
-
-		if isError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
+		result, _ := evalChain(node, env)
+		return result
 
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
@@ -135,6 +166,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IntegerLiteral:
 		// 순회는 언제나 트리 최상단에서 시작해야 한다.
 		return &object.Integer{Value: node.Value}
+
+	case *ast.BigIntegerLiteral:
+		return object.NewInteger(node.Value)
+
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	}
 	return nil
 }
@@ -175,11 +212,27 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return object.NewInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value} // 새로 객체를 할당
+	case *object.BigInteger:
+		return object.NewInteger(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value} // 새로 객체를 할당
+}
+
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIG_INTEGER_OBJ
+}
+
+func isNumber(obj object.Object) bool {
+	return isInteger(obj) || obj.Type() == object.FLOAT_OBJ
 }
 
 func evalInfixExpression(operator string,
@@ -188,13 +241,22 @@ func evalInfixExpression(operator string,
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 
-	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(operator, left, right)
+	// 한쪽이 큰 정수면 math/big으로 계산
+	case isInteger(left) && isInteger(right):
+		return evalBigIntegerInfixExpression(operator, left, right)
 
+	// 한쪽이라도 실수면 실수로 계산
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
+
+	// 문자열, 배열, 해시는 값으로 비교한다.
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
+
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
@@ -210,26 +272,168 @@ func evalIntegerInfixExpression(operator string,
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	var result int64
+	ok := true
+
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		result, ok = object.AddInt64(leftVal, rightVal)
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		result, ok = object.SubInt64(leftVal, rightVal)
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		result, ok = object.MulInt64(leftVal, rightVal)
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			ok = false
+		} else {
+			result = leftVal / rightVal
+		}
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		result = leftVal % rightVal
+	case "&":
+		result = leftVal & rightVal
+	case "|":
+		result = leftVal | rightVal
+	case "^":
+		result = leftVal ^ rightVal
+	case "<<":
+		if rightVal >= 0 && rightVal < 63 && (leftVal<<rightVal)>>rightVal == leftVal {
+			result = leftVal << rightVal
+		} else {
+			ok = false
+		}
+	case ">>":
+		if rightVal >= 0 {
+			result = leftVal >> rightVal
+		} else {
+			ok = false
+		}
+	// 거듭제곱은 쉽게 넘치므로 언제나 큰 정수로 계산하고 결과를 다시 줄인다.
+	case "**":
+		ok = false
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
 
+	// int64 범위를 넘으면 큰 정수로 다시 계산
+	if !ok {
+		return evalBigIntegerInfixExpression(operator, left, right)
+	}
+	return &object.Integer{Value: result}
+}
+
+// 결과가 int64에 들어가면 다시 Integer가 된다.
+func evalBigIntegerInfixExpression(operator string,
+	left object.Object, right object.Object) object.Object {
+
+	leftVal, _ := object.ToBigInt(left)
+	rightVal, _ := object.ToBigInt(right)
+	result := new(big.Int)
+
+	switch operator {
+	case "+":
+		result.Add(leftVal, rightVal)
+	case "-":
+		result.Sub(leftVal, rightVal)
+	case "*":
+		result.Mul(leftVal, rightVal)
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		result.Quo(leftVal, rightVal)
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		result.Rem(leftVal, rightVal)
+	case "&":
+		result.And(leftVal, rightVal)
+	case "|":
+		result.Or(leftVal, rightVal)
+	case "^":
+		result.Xor(leftVal, rightVal)
+	case "**":
+		obj, err := object.PowInteger(leftVal, rightVal)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return obj
+	case "<<", ">>":
+		obj, err := object.ShiftInteger(leftVal, rightVal, operator == "<<")
+		if err != nil {
+			return newError("%s", err)
+		}
+		return obj
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+	return object.NewInteger(result)
+}
+
+func evalFloatInfixExpression(operator string,
+	left object.Object, right object.Object) object.Object {
+
+	leftVal, _ := object.ToFloat(left)
+	rightVal, _ := object.ToFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -288,16 +492,88 @@ func evalBlockStatements(block *ast.BlockStatement, env *object.Environment) obj
 		result = Eval(statement, env)
 
 		// object.RETURN_VALUE_OBJ이면 풀지않고 그대로 반환
+		// break, continue도 반복문까지 그대로 전달
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || isLoopControl(result) {
 				return result
 			}
 		}
 	}
+	// 비어 있거나 let으로 끝나는 블록은 값이 없으므로 가상 머신처럼 NULL을 반환
+	if result == nil {
+		return NULL
+	}
 	return result
 }
 
+// 반복문은 값을 만들지 않기 때문에 NULL을 반환
+// 본문에서 나온 break, continue는 여기서 처리하고 return과 에러는 바깥으로 전달한다.
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(ws.Body, env)
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+				return result
+			case object.BREAK_OBJ:
+				return NULL
+			}
+		}
+	}
+}
+
+// 반복 변수는 let처럼 현재 환경에 바인딩되므로 반복문이 끝난 뒤에도 남는다.
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+	iterator, ok := object.Iterate(iterable)
+	if !ok {
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	for {
+		if fs.Key != nil {
+			key, value, ok := iterator.NextPair()
+			if !ok {
+				return NULL
+			}
+			env.Set(fs.Key.Value, key)
+			env.Set(fs.Value.Value, value)
+		} else {
+			value, ok := iterator.Next()
+			if !ok {
+				return NULL
+			}
+			env.Set(fs.Value.Value, value)
+		}
+
+		result := Eval(fs.Body, env)
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+				return result
+			case object.BREAK_OBJ:
+				return NULL
+			}
+		}
+	}
+}
+
+func isLoopControl(obj object.Object) bool {
+	return obj == BREAK || obj == CONTINUE
+}
+
 // 내부 에러처리: 잘못된 연산자를 쓰거나 지원되지 않은 연산을 한다거나 혹은 그 밖에 실행 중에 일어 날수 있는 사용자 또는 내부에러를 말함
 // 앞서 작성한 코드에서 어떤 동작으로 처리해야 할지 몰라 그냥 NULL을 반환한 모든 코드를 대체 할것이다.
 func newError(format string, a ...interface{}) *object.Error {
@@ -325,6 +601,110 @@ func evalIdentifier(node *ast.Identifier,
 	return newError("identifier not found: " + node.Value)
 }
 
+// x += y는 x의 현재 값과 y로 x + y를 계산해 x에 대입한다.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	if target, ok := node.Target.(*ast.IndexExpression); ok {
+		return evalIndexAssignExpression(node, target, env)
+	}
+	name := node.Target.(*ast.Identifier).Value
+
+	current, ok := env.Get(name)
+	if !ok {
+		if _, ok := builtins[name]; ok {
+			return newError("cannot assign to %s", name)
+		}
+		return newError("identifier not found: " + name)
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+	if node.Operator != "=" {
+		val = evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val)
+		if isError(val) {
+			return val
+		}
+	}
+
+	env.Assign(name, val)
+	return val
+}
+
+// 배열이나 해시, 인덱스, 값 순서로 평가하고 배열이나 해시를 그 자리에서 바꾼다.
+func evalIndexAssignExpression(node *ast.AssignExpression, target *ast.IndexExpression, env *object.Environment) object.Object {
+	left := Eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+	index := Eval(target.Index, env)
+	if isError(index) {
+		return index
+	}
+
+	var current object.Object
+	if node.Operator != "=" {
+		current = evalIndexExpression(left, index)
+		if isError(current) {
+			return current
+		}
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+	if node.Operator != "=" {
+		val = evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val)
+		if isError(val) {
+			return val
+		}
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+		if i.Value < 0 || i.Value >= int64(len(left.Elements)) {
+			return newError("index out of range: %d (length %d)", i.Value, len(left.Elements))
+		}
+		if left.Frozen {
+			return newError("cannot modify array used as hash key")
+		}
+		left.Elements[i.Value] = val
+
+	case *object.Hash:
+		key, ok := object.ToHashable(index)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		left.Set(key, val)
+
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+	return val
+}
+
+// 왼쪽 피연산자로 결과가 정해지면 오른쪽을 평가하지 않고 왼쪽 값을 돌려준다.
+// ??는 왼쪽이 null일 때만 오른쪽을 평가한다.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	if node.Operator == "??" {
+		if left != NULL {
+			return left
+		}
+	} else if isTruthy(left) == (node.Operator == "||") {
+		return left
+	}
+	return Eval(node.Right, env)
+}
+
 func evalExpressions(exp []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
@@ -342,6 +722,10 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments to %s: want=%d, got=%d",
+				fn.Describe(), len(fn.Parameters), len(args))
+		}
 		extendEnv := extendFunctionEnv(fn, args)
 		evaluated := Eval(fn.Body, extendEnv)
 		return unwrapReturnValue(evaluated)
@@ -376,22 +760,99 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
+// 각 부분을 문자열로 바꿔서 순서대로 이어 붙인다.
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out bytes.Buffer
+
+	for _, part := range node.Parts {
+		val := Eval(part, env)
+		if isError(val) {
+			return val
+		}
+		out.WriteString(object.ToString(val).Value)
+	}
+	return &object.String{Value: out.String()}
+}
+
 func evalStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
-	if operator != "+" {
+	cmp, _ := object.Compare(left, right)
+
+	switch operator {
+	case "+":
+		leftVal := left.(*object.String).Value
+		rightVal := right.(*object.String).Value
+		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(cmp < 0)
+	case ">":
+		return nativeBoolToBooleanObject(cmp > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(cmp <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(cmp >= 0)
+	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
+}
 
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
+// h?.[a][b](c)처럼 인덱스와 호출이 이어진 체인을 앞 고리부터 평가한다.
+// ?.가 null을 만나면 체인의 나머지 고리를 평가하지 않고 체인 전체가 null이 되며, 이때 skipped가 true이다.
+// 인덱스와 인수는 새 체인이라서 그 안의 ?.는 바깥 체인을 건너뛰지 않는다.
+func evalChain(node ast.Expression, env *object.Environment) (result object.Object, skipped bool) {
+	// 앞 고리는 Eval을 거치지 않으므로 Eval처럼 에러에 위치를 붙인다.
+	defer func() {
+		if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+			err.Pos = node.Pos()
+		}
+	}()
 
-	return &object.String{Value: leftVal + rightVal}
+	switch node := node.(type) {
+	case *ast.IndexExpression:
+		left, skipped := evalChain(node.Left, env)
+		if skipped || isError(left) {
+			return left, skipped
+		}
+		if node.Optional && left == NULL {
+			return NULL, true
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index, false
+		}
+		return evalIndexExpression(left, index), false
+
+	case *ast.CallExpression:
+		// quote의 인수는 평가하지 않는다.
+		if node.Function.TokenLiteral() == "quote" {
+			if len(node.Arguments) != 1 {
+				return newError("wrong number of arguments to quote. got=%d, want=1", len(node.Arguments)), false
+			}
+			return quote(node.Arguments[0], env), false
+		}
+		function, skipped := evalChain(node.Function, env)
+		if skipped || isError(function) {
+			return function, skipped
+		}
+		if node.Optional && function == NULL {
+			return NULL, true
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0], false
+		}
+		return applyFunction(function, args), false
+
+	default:
+		return Eval(node, env), false
+	}
 }
 
 func evalIndexExpression(left object.Object, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
-
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -410,39 +871,48 @@ func evalArrayIndexExpression(array object.Object, index object.Object) object.O
 	return arrayObject.Elements[idx]
 }
 
+// 문자열의 인덱스는 바이트가 아니라 문자 단위, 범위를 벗어나면 NULL을 반환
+func evalStringIndexExpression(str object.Object, index object.Object) object.Object {
+	ch := str.(*object.String).CharAt(index.(*object.Integer).Value)
+	if ch == nil {
+		return NULL
+	}
+	return ch
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	// 컴파일러와 마찬가지로 키와 값을 소스에 나온 순서대로 평가한다.
+	for _, pairNode := range node.Pairs {
+		key := Eval(pairNode.Key, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.ToHashable(key)
 
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
-		value := Eval(valueNode, env)
+		value := Eval(pairNode.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalHashIndexExpression(hash object.Object, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
-	key, ok := index.(object.Hashable)
+	key, ok := object.ToHashable(index)
 	if !ok {
-		return newError("unusable as has key: %s", index.Type())
+		return newError("unusable as hash key: %s", index.Type())
 	}
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Get(key)
 	if !ok {
 		return NULL
 	}
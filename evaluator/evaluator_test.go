@@ -4,6 +4,7 @@ import (
 	"MonkeyKids/lexer"
 	"MonkeyKids/object"
 	"MonkeyKids/parser"
+	"fmt"
 	"testing"
 )
 
@@ -59,6 +60,192 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"3.14", 3.14},
+		{"1e-3", 0.001},
+		{"-2.5", -2.5},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 / 4", 2},
+		{"10 / 4.0", 2.5},
+		{"2 * 1.5 - 1", 2.0},
+		{"1 < 1.5", true},
+		{"1.5 > 2", false},
+		{"1 == 1.0", true},
+		{"0.1 + 0.2 == 0.3", false},
+		{"int(-2.9)", -2},
+		{"float(3)", 3.0},
+		{`float("1e2")`, 100.0},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestEvalBigIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"9223372036854775807 * 2 / 2", 9223372036854775807},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(25)", "15511210043330985984000000"},
+		{"9223372036854775807 + 1 > 9223372036854775807", true},
+		{"9223372036854775807 + 1 == 9223372036854775807 + 1", true},
+		{`{9223372036854775807 + 1: 1}[9223372036854775807 * 2 / 2 + 1]`, 1},
+		// int64 범위를 넘는 리터럴
+		{"9223372036854775808", "9223372036854775808"},
+		{"18446744073709551614", "18446744073709551614"},
+		{"-9223372036854775808", -9223372036854775807 - 1},
+		{"9223372036854775808 == 2 ** 63", true},
+		{"18446744073709551614 / 2", 9223372036854775807},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			result, ok := evaluated.(*object.BigInteger)
+			if !ok {
+				t.Errorf("object is not BigInteger. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if result.Inspect() != expected {
+				t.Errorf("object has wrong value. got=%s, want=%s", result.Inspect(), expected)
+			}
+		}
+	}
+
+	evaluated := testEval("10 / (1 - 1)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "division by zero" {
+		t.Errorf("expected division by zero error. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"2 ** 64", "18446744073709551616"},
+		{"2 ** -1", 0.5},
+		{"12 & 10", 8},
+		{"12 | 10", 14},
+		{"12 ^ 10", 6},
+		{"1 << 63", "9223372036854775808"},
+		{"-7 >> 1", -4},
+		{"(1 << 100) >> 98", 4},
+		{"(2 ** 64 + 5) % 2 ** 32", 5},
+		{"7.5 % 2", 1.5},
+		{"4 ** 0.5", 2.0},
+		{"1 <= 1", true},
+		{"1 >= 2", false},
+		{"1 <= 1.5", true},
+		{"2 ** 64 >= 2 ** 63", true},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case string:
+			result, ok := evaluated.(*object.BigInteger)
+			if !ok {
+				t.Errorf("object is not BigInteger. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if result.Inspect() != expected {
+				t.Errorf("object has wrong value. got=%s, want=%s", result.Inspect(), expected)
+			}
+		}
+	}
+}
+
+// 이항 연산자는 피연산자를 왼쪽부터 평가한다.
+func TestEvaluationOrder(t *testing.T) {
+	operators := []string{"+", "-", "*", "/", "%", "**", "&", "|", "^", "<<", ">>", "<", ">", "<=", ">=", "==", "!=", "&&", "||", "??"}
+
+	type orderTest struct {
+		input    string
+		expected interface{}
+	}
+	tests := []orderTest{
+		{`let s = ""; let l = fn(name, v) { s += name; v }; l("a", 1) + l("b", 2) * l("c", 3); s`, "abc"},
+		{"let x = 1; let f = fn() { x = 10; 1 }; x += f(); x", 2},
+		{`let s = ""; let l = fn(name, v) { s += name; v }; let a = [0]; l("a", a)[l("b", 0)] += l("c", 1); s`, "abc"},
+		// 해시 리터럴의 키와 값은 소스에 나온 순서대로 평가한다.
+		{`let s = ""; let l = fn(name, v) { s += name; v }; {l("z", 1): l("y", 2), l("b", 3): l("a", 4)}; s`, "zyba"},
+		{`{1: "first", 1: "second"}[1]`, "second"},
+	}
+	for _, op := range operators {
+		// ||는 왼쪽이 거짓이어야, ??는 왼쪽이 null이어야 오른쪽을 평가한다.
+		left := "6"
+		switch op {
+		case "||":
+			left = "false"
+		case "??":
+			left = "null"
+		}
+		tests = append(tests, orderTest{fmt.Sprintf(`let s = ""; let l = fn(name, v) { s += name; v }; l("a", %s) %s l("b", 3); s`, left, op), "ab"})
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("wrong evaluation order for %q. got=%q, want=%q", tt.input, str.Value, expected)
+			}
+		}
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -75,7 +262,7 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"1 == 2", false},
 		{"1 != 2", true},
 		{"true == true", true},
-		{"false == false", false},
+		{"false == false", true},
 		{"true == false", false},
 		{"true != false", true},
 		{"false != true", true},
@@ -90,6 +277,41 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+// 문자열, 배열, 해시, null은 값으로 비교한다.
+func TestValueEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"a" + "b" == "ab"`, true},
+		{`"a" < "b"`, true},
+		{`"b" > "a"`, true},
+		{`"ab" < "a"`, false},
+		{`"a" <= "a"`, true},
+		{`"a" >= "b"`, false},
+		{`[1, [2, "x"]] == [1, [2, "x"]]`, true},
+		{`[1, 2] == [2, 1]`, false},
+		{`[1] == [1, 1]`, false},
+		{`[1] == [1.0]`, true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`{} == []`, false},
+		{`null == null`, true},
+		{`null == false`, false},
+		{`let a = [1]; a[0] = a; let b = [1]; b[0] = b; a == b`, true},
+		{`let f = fn() { 1 }; f == f`, true},
+		{`fn() { 1 } == fn() { 1 }`, false},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -171,6 +393,273 @@ func TestReturnState(t *testing.T) {
 	}
 }
 
+func TestBareReturn(t *testing.T) {
+	tests := []string{
+		"return; 9;",
+		"let f = fn() { return\n 10 }; f()",
+		"let f = fn(x) { if (x) { return }\n x }; f(true)",
+	}
+	for _, input := range tests {
+		evaluated := testEval(input)
+		testNullObject(t, evaluated)
+	}
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && 2", 2},
+		{"0 && 2", 2},
+		{"false && 2", false},
+		{"1 || 2", 1},
+		{"if (false) { 1 } || 3", 3},
+		{"let x = 0; x != 0 && 10 / x > 1", false},
+		{"let x = 5; x != 0 && 10 / x > 1", true},
+		{"let n = 0; let f = fn() { n += 1; true }; false && f(); true || f(); n", 0},
+		{"let n = 0; let f = fn() { n += 1; false }; f() || f() && f(); n", 2},
+		{"false || -true", "unknown operator: -BOOLEAN"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestNullOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null", nil},
+		{"null == null", true},
+		{"!null", true},
+		{"null ?? 1", 1},
+		{"false ?? 1", false},
+		{"0 ?? 1", 0},
+		{"null ?? null ?? 3", 3},
+		{`let h = {"a": 1}; h["b"] ?? 2`, 2},
+		{`let h = null; h?.["a"]`, nil},
+		{`let h = {"a": {"b": 2}}; h?.["a"]?.["b"]`, 2},
+		{"let f = fn(x) { x * 2 }; f?.(21)", 42},
+		{"let f = null; f?.(21)", nil},
+		{"let n = 0; let g = fn() { n += 1; n }; 1 ?? g(); null?.[g()]; null?.(g()); n", 0},
+		{"let n = 0; let g = fn() { n += 1; n }; null ?? g(); [1]?.[g() - 1]; n", 2},
+		{"null?.[0] ?? -true", "unknown operator: -BOOLEAN"},
+		// ?.가 null을 만나면 체인 전체를 건너뛴다.
+		{`let h = {}; h["a"]?.["b"]["c"]`, nil},
+		{`let h = {"a": null}; h["a"]?.["b"]["c"](1)`, nil},
+		{"let f = null; f?.(1)(2)[0]", nil},
+		{`let h = {"a": {"b": {"c": 3}}}; h["a"]?.["b"]["c"]`, 3},
+		{"let n = 0; let g = fn() { n += 1; n }; null?.[g()][g()](g()); n", 0},
+		// 인덱스와 인수 안의 체인은 바깥 체인을 건너뛰지 않는다.
+		{`[1, 2][null?.[0]["a"] ?? 1]`, 2},
+		{"let f = fn(x) { x ?? 7 }; f(null?.[0][1])", 7},
+		{`let h = {"a": {}}; h["a"]?.["b"]["c"]`, "index operator not supported: NULL"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn() { while (false) { 1 } }; f()`, nil},
+		{`let f = fn() { while (true) { return 5; } }; f()`, 5},
+		{`let f = fn() { while (true) { if (true) { break; } } 7 }; f()`, 7},
+		{`let f = fn(n) { while (true) { if (true) { if (n > 0) { break; } } } n }; f(3)`, 3},
+		{`let f = fn(xs) { while (len(xs) > 0) { return first(xs); } 0 }; f([4, 5])`, 4},
+		{`while (true) { break; } 2`, 2},
+		{`let f = fn() { while (true) { let g = fn() { 1 }; if (g() == 1) { break; } continue; } 3 }; f()`, 3},
+		{`let i = 0; let n = 0; while (i < 5000) { i += 1; if (i % 2 == 0) { continue } else { if (i > 4000) { break } } n += 1 }; n`, 2000},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if expected, ok := tt.expected.(int); ok {
+			testIntegerObject(t, evaluated, int64(expected))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn() { for (x in []) { return 1; } 0 }; f()`, 0},
+		{`let f = fn(xs) { for (i, x in xs) { if (x > 2) { return i; } } -1 }; f([1, 2, 3, 4])`, 2},
+		{`let f = fn() { for (i, ch in "héllo") { if (i == 1) { return ch; } } }; f()`, "é"},
+		{`let f = fn() { for (k, v in {"a": 1}) { return k + "${v}"; } }; f()`, "a1"},
+		{`let f = fn() { for (k in {"a": 1}) { return k; } }; f()`, "a"},
+		// 해시는 키를 넣은 순서대로 순회한다.
+		{`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; let s = ""; for (k, v in h) { s += k + "${v}"; } s`, "b4a2c3"},
+		{`let f = fn() { for (x in range(10, 0, -3)) { if (x < 5) { return x; } } }; f()`, 4},
+		{`let f = fn() { for (x in range(3)) { for (y in range(3)) { if (y == 1) { break; } } if (x == 2) { return x * 10; } } }; f()`, 20},
+		{`let f = fn() { for (x in range(5)) { if (x < 3) { continue; } return x; } }; f()`, 3},
+		{`let f = fn() { for (x in [1, 2, 3]) { } x }; f()`, 3},
+		{`let sum = 0; for (x in range(5)) { let sum = sum + x; } sum`, 10},
+		{`for (x in [1, 2]) { break; }`, nil},
+		{`let n = 0; for (x in range(5000)) { if (x % 2 == 0) { continue } n += 1 }; n`, 2500},
+		{`for (x in 5) { }`, "cannot iterate over INTEGER"},
+		{`for (i in range(0, 10, 0)) { }`, "range step must not be zero"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value. got=%q, want=%q", obj.Value, expected)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. got=%q, want=%q", obj.Message, expected)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let x = 1; x = 2; x`, 2},
+		{`let x = 1; x = x + 1`, 2},
+		{`let a = 1; let b = 2; a = b = 3; a + b`, 6},
+		{`let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x`, 6},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{`let sum = 0; for (x in range(5)) { sum += x; } sum`, 10},
+		{`let f = fn(n) { let i = 0; while (i < n) { i += 1; } i }; f(4)`, 4},
+		{`let f = fn(n) { n = n * 2; n }; f(21)`, 42},
+		{`let x = 1; let f = fn() { x }; let x = 2; f()`, 2},
+		{`let f = fn() { f = 1 }; f(); f`, 1},
+		{`let g = fn() { let f = fn() { f = 1; f }; f() }; g()`, 1},
+		{`let g = fn() { let f = fn() { fn() { f = 2 } }; f()(); f }; g()`, 2},
+		{`let newCounter = fn() { let count = 0; fn() { count += 1 } }; let c = newCounter(); c(); c(); c()`, 3},
+		{`let newCounter = fn() { let count = 0; fn() { count += 1 } }; let a = newCounter(); let b = newCounter(); a(); a(); b(); a()`, 3},
+		{`let f = fn() { let x = 1; let g = fn() { x }; x = 5; g() }; f()`, 5},
+		{`let f = fn() { let x = 0; let g = fn() { fn() { x += 10 } }; g()(); g()(); x }; f()`, 20},
+		{`let f = fn(x) { let x = 3; x }; f(1)`, 3},
+		{`let fns = []; for (i in range(3)) { let j = i; fns = push(fns, fn() { j }); } let total = 0; for (g in fns) { total += g(); } total`, 6},
+		{`y = 1`, "identifier not found: y"},
+		{`len = 1`, "cannot assign to len"},
+		{`let x = 1; x += "a"`, "type mismatch: INTEGER + STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value. got=%q, want=%q", obj.Value, expected)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. got=%q, want=%q", obj.Message, expected)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestIndexAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = [1, 2, 3]; a[1] = 5; a[1]", 5},
+		{"let a = [1, 2, 3]; a[2] = 7", 7},
+		{"let a = [1, 2]; let b = a; b[0] = 9; a[0]", 9},
+		{"let a = [[1], [2]]; a[1][0] += 10; a[1][0]", 12},
+		{"let h = {\"k\": 1}; h[\"k\"] = 2; h[\"k\"]", 2},
+		{"let h = {}; h[1] = 5; h[1] *= 3; h[1]", 15},
+		{"let set = fn(xs, i, v) { xs[i] = v }; let a = [0, 0]; set(a, 1, 4); a[1]", 4},
+		{"let a = [0, 0, 0]; for (i, x in a) { a[i] = i * 2; } a[2]", 4},
+		{"let i = 0; let next = fn() { i += 1; i - 1 }; let a = [10, 20]; a[next()] += 1; a[0] + i", 12},
+		{"let a = [1]; a[1] = 2", "index out of range: 1 (length 1)"},
+		{"let a = [1]; a[\"x\"] = 2", "array index must be INTEGER, got STRING"},
+		{"let s = \"ab\"; s[0] = \"c\"", "index assignment not supported: STRING"},
+		{"let h = {}; h[fn() { 1 }] = 2", "unusable as hash key: FUNCTION"},
+		{"{[1, fn() { 1 }]: 2}", "unusable as hash key: ARRAY"},
+		{"let a = [1]; a[0] = a; {a: 1}", "unusable as hash key: ARRAY"},
+		{"let h = {}; h[0.0 / 0.0] = 1", "unusable as hash key: FLOAT"},
+		{"{[0.0 / 0.0]: 1}", "unusable as hash key: ARRAY"},
+		{"for (k in {[1]: 1}) { k[0] = 2 }", "cannot modify array used as hash key"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -187,8 +676,14 @@ func TestErrorHandling(t *testing.T) {
 		{`if (10 > 1) {if (10 > 1) { return true + false; } return 1; }`,
 			"unknown operator: BOOLEAN + BOOLEAN"},
 		{"foobar", "identifier not found: foobar"},
-		{`"hello" - "World"`, "unknown operator: STRING + STRING"},
+		{`"hello" - "World"`, "unknown operator: STRING - STRING"},
 		{`{"name":"Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+		{"10 % 0", "division by zero"},
+		{"1 << -1", "negative shift count: -1"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"2 ** 100000000", "integer result too large"},
+		{"fn(a) { a }()", "wrong number of arguments to fn(a) defined at 1:1-1:11: want=1, got=0"},
+		{"let fib = fn(n) {\n  n\n};\nfib(1, 2)", "wrong number of arguments to fib(n) defined at 1:11-3:1: want=1, got=2"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -294,6 +789,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("hello world")`, 11},
 		{`len(1)`, `argument to 'len' not supported, got INTEGER`},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`keys([1])`, "argument to 'keys' must be HASH, got ARRAY"},
+		{`values({}, {})`, "wrong number of arguments. got=2, want=1"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -304,7 +801,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		case string:
 			errObj, ok := evaluated.(*object.Error)
 			if !ok {
-				t.Errorf("object is not Error. got=%1, got=%q", expected, evaluated)
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
 				continue
 			}
 			if errObj.Message != expected {
@@ -314,6 +811,61 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+// keys와 values는 키를 넣은 순서대로 돌려준다.
+func TestHashKeysAndValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; keys(h)`, `["b", "a", "c"]`},
+		{`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; values(h)`, "[4, 2, 3]"},
+		{`keys({1: "x", [1, 2]: "y", true: "z"})`, "[1, [1, 2], true]"},
+		{`keys({})`, "[]"},
+		{`values({})`, "[]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result. expected=%q, got=%q", tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestUnicodeStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("한글")`, 2},
+		{`let 이름 = "철수"; 이름`, "철수"},
+		{`"한글"[1]`, "글"},
+		{`"한글"[2]`, nil},
+		{`first("한글")`, "한"},
+		{`last("한글")`, "글"},
+		{`rest("한글")`, "글"},
+		{`rest("")`, nil},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestArrayLiteral(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 	evaluated := testEval(input)
@@ -374,15 +926,16 @@ func TestHashLiteral(t *testing.T) {
 		FALSE.HashKey():                            6,
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for _, pair := range result.OrderedPairs() {
+		expectedValue, ok := expected[pair.Key.(object.Hashable).HashKey()]
 
 		if !ok {
-			t.Errorf("no pair for given key in Pairs")
+			t.Errorf("unexpected key in Pairs: %s", pair.Key.Inspect())
+			continue
 		}
 		testIntegerObject(t, pair.Value, expectedValue)
 	}
@@ -411,6 +964,106 @@ func TestHashIndexExpression(t *testing.T) {
 		}
 	}
 }
+
+func TestCompoundHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let visited = {}; visited[[1, 2]] = 5; visited[[1, 2]]`, 5},
+		{`{[1, [2, "a"]]: 5}[[1, [2, "a"]]]`, 5},
+		{`{[1, 2]: 5}[[2, 1]]`, nil},
+		{`{[]: 5}[[]]`, 5},
+		{`{null: 5}[null]`, 5},
+		{`let h = {[1]: 1}; h[[1]] = 2; let n = 0; for (k in h) { n += 1; } n * 10 + h[[1]]`, 12},
+		// 키로 넣은 배열을 나중에 바꿔도 해시에는 넣을 때의 값이 키로 남는다.
+		{`let k = [1, 2]; let h = {}; h[k] = 5; k[0] = 9; h[[1, 2]]`, 5},
+		{`let k = [1, 2]; let h = {}; h[k] = 5; k[0] = 9; h[k]`, nil},
+		// 숫자 키는 == 처럼 타입과 상관없이 값으로 찾는다.
+		{`{1: 5}[1.0]`, 5},
+		{`{1.0: 5}[1]`, 5},
+		{`{-0.0: 5}[0]`, 5},
+		{`{[1, [2]]: 5}[[1.0, [2.0]]]`, 5},
+		{`{[1.0]: 5}[[1]]`, 5},
+		{`let big = 9223372036854775807 + 1; {big: 5}[2.0 ** 63]`, 5},
+		{`{1.5: 5}[1]`, nil},
+		{`let h = {1: 1}; h[1.0] = 2; let n = 0; for (k in h) { n += 1; } n * 10 + h[1]`, 12},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+// 모든 문자열이 같은 해시 값을 갖게 해서 충돌한 키를 실제 값으로 구분하는지 확인한다.
+// big과 big + 1은 실수로 바꾸면 같은 값이라서 원래 같은 해시 값을 갖는다.
+func TestHashCollisions(t *testing.T) {
+	hashBytes := object.HashBytes
+	object.HashBytes = func([]byte) uint64 { return 0 }
+	defer func() { object.HashBytes = hashBytes }()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"a": 1, "b": 2}["a"]`, 1},
+		{`{"a": 1, "b": 2}["b"]`, 2},
+		{`{"a": 1, "b": 2}["c"]`, nil},
+		{`let h = {"a": 1}; h["b"] = 2; h["a"] = 3; h["a"] * 10 + h["b"]`, 32},
+		{`let big = 9223372036854775807 + 1; let h = {big: 1, big + 1: 2}; h[big + 1]`, 2},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+
+	evaluated := testEval(`{"a": 1, "b": 2, "a": 3}`)
+	hash, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+	if hash.Inspect() != `{"a": 3, "b": 2}` {
+		t.Errorf("hash has wrong Inspect. got=%q", hash.Inspect())
+	}
+}
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\tb\n\"c\"\\"`, "a\tb\n\"c\"\\"},
+		{`"\u{D55C}\u{AE00}"`, "한글"},
+		{`let name = "Monkey"; let age = 3; "Hello ${name}, you are ${age + 1}"`, "Hello Monkey, you are 4"},
+		{`"${[1, true]} ${fn(x) { x }(5)} ${"in" + "ner"}"`, "[1, true] 5 inner"},
+		// 배열과 해시 안의 문자열은 따옴표로 감싸고, 함수는 이름과 파라미터를 보여준다.
+		{`"${["a, b", {"k": "v"}]}"`, `["a, b", {"k": "v"}]`},
+		{`let fib = fn(n) { n }; "${fib} ${fn(x, y) { x }} ${len}"`, "<fn fib(n)> <fn(x, y)> <builtin len>"},
+		{`"${"a${1}"}b"`, "a1b"},
+		{`"\${x}"`, "${x}"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!"`
 	evaluated := testEval(input)
@@ -424,3 +1077,27 @@ func TestStringConcatenation(t *testing.T) {
 		t.Errorf("String has Wrong value. got=%q", str.Value)
 	}
 }
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true;", "1:3"},
+		{"let x = 1;\nfoobar", "2:1"},
+		{"if (true) {\n  -true\n}", "2:3"},
+		{"let f = fn() { len(1) };\nf()", "1:19"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Pos.String() != tt.expected {
+			t.Errorf("wrong error position. want=%s, got=%s", tt.expected, errObj.Pos)
+		}
+	}
+}
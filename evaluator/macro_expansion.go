@@ -0,0 +1,132 @@
+package evaluator
+
+import (
+	"MonkeyKids/ast"
+	"MonkeyKids/object"
+	"fmt"
+)
+
+// 매크로 확장
+// 파싱과 평가(또는 컴파일) 사이에서 돌아가는 단계이다.
+// 1. DefineMacros: 최상위의 let <name> = macro(...) 명령문을 찾아 매크로 환경에 저장하고 프로그램에서 지운다.
+// 2. ExpandMacros: 매크로 호출을 찾아 인수를 평가하지 않고 Quote로 넘겨 매크로 몸체를 평가하고, 돌려받은 AST로 호출을 바꾼다.
+// 확장이 끝난 프로그램에는 매크로가 남지 않으므로 평가기와 컴파일러 어느 쪽으로도 실행할 수 있다.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	definitions := []int{}
+
+	for i, statement := range program.Statements {
+		if isMacroDefinition(statement) {
+			addMacro(statement, env)
+			definitions = append(definitions, i)
+		}
+	}
+
+	// 뒤에서부터 지워야 앞의 인덱스가 밀리지 않는다.
+	for i := len(definitions) - 1; i >= 0; i-- {
+		definitionIndex := definitions[i]
+		program.Statements = append(
+			program.Statements[:definitionIndex],
+			program.Statements[definitionIndex+1:]...,
+		)
+	}
+}
+
+func isMacroDefinition(node ast.Statement) bool {
+	letStatement, ok := node.(*ast.LetStatement)
+	if !ok {
+		return false
+	}
+	_, ok = letStatement.Value.(*ast.MacroLiteral)
+	return ok
+}
+
+func addMacro(stmt ast.Statement, env *object.Environment) {
+	letStatement, _ := stmt.(*ast.LetStatement)
+	macroLiteral, _ := letStatement.Value.(*ast.MacroLiteral)
+
+	macro := &object.Macro{
+		Parameters: macroLiteral.Parameters,
+		Env:        env,
+		Body:       macroLiteral.Body,
+	}
+	env.Set(letStatement.Name.Value, macro)
+}
+
+// 매크로 호출을 매크로가 돌려준 AST로 바꾼 프로그램을 돌려준다.
+// 매크로가 에러를 내거나 Quote가 아닌 값을 돌려주면 처음 만난 에러를 돌려준다.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, error) {
+	var err error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		if err != nil {
+			return node
+		}
+		callExpression, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+		macro, ok := isMacroCall(callExpression, env)
+		if !ok {
+			return node
+		}
+		if len(callExpression.Arguments) != len(macro.Parameters) {
+			err = fmt.Errorf("%s: wrong number of arguments to macro %s. got=%d, want=%d",
+				callExpression.Pos(), callExpression.Function, len(callExpression.Arguments), len(macro.Parameters))
+			return node
+		}
+
+		args := quoteArgs(callExpression)
+		evalEnv := extendMacroEnv(macro, args)
+
+		evaluated := unwrapReturnValue(Eval(macro.Body, evalEnv))
+		switch evaluated := evaluated.(type) {
+		case *object.Quote:
+			return evaluated.Node
+		case *object.Error:
+			err = fmt.Errorf("%s: macro %s failed: %s", callExpression.Pos(), callExpression.Function, evaluated.Message)
+		default:
+			err = fmt.Errorf("%s: macro %s must return a quoted AST node, got %s",
+				callExpression.Pos(), callExpression.Function, evaluated.Type())
+		}
+		return node
+	})
+	return expanded, err
+}
+
+func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	identifier, ok := exp.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		return nil, false
+	}
+
+	return macro, true
+}
+
+// 매크로의 인수는 평가하지 않고 그대로 Quote로 감싼다.
+func quoteArgs(exp *ast.CallExpression) []*object.Quote {
+	var args []*object.Quote
+
+	for _, a := range exp.Arguments {
+		args = append(args, &object.Quote{Node: a})
+	}
+	return args
+}
+
+func extendMacroEnv(macro *object.Macro, args []*object.Quote) *object.Environment {
+	extended := object.NewEnclosedEnvironment(macro.Env)
+
+	for paramIdx, param := range macro.Parameters {
+		extended.Set(param.Value, args[paramIdx])
+	}
+	return extended
+}
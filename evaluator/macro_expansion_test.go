@@ -0,0 +1,202 @@
+package evaluator
+
+import (
+	"MonkeyKids/ast"
+	"MonkeyKids/lexer"
+	"MonkeyKids/object"
+	"MonkeyKids/parser"
+	"testing"
+)
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	`
+
+	env := object.NewEnvironment()
+	program := testParseProgram(input)
+
+	DefineMacros(program, env)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	_, ok := env.Get("number")
+	if ok {
+		t.Fatalf("number should not be defined")
+	}
+	_, ok = env.Get("function")
+	if ok {
+		t.Fatalf("function should not be defined")
+	}
+
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in environment.")
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("Wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+
+	if macro.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", macro.Parameters[0])
+	}
+	if macro.Parameters[1].String() != "y" {
+		t.Fatalf("parameter is not 'y'. got=%q", macro.Parameters[1])
+	}
+
+	expectedBody := "(x + y)"
+
+	if macro.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, macro.Body.String())
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`
+			let infixExpression = macro() { quote(1 + 2); };
+
+			infixExpression();
+			`,
+			`(1 + 2)`,
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+			reverse(2 + 2, 10 - 5);
+			`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`
+			let unless = macro(condition, consequence, alternative) {
+				quote(if (!(unquote(condition))) {
+					unquote(consequence);
+				} else {
+					unquote(alternative);
+				});
+			};
+
+			unless(10 > 5, puts("not greater"), puts("greater"));
+			`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+		{
+			// 같은 매크로를 여러 번 써도 매크로 몸체는 바뀌지 않는다.
+			`
+			let double = macro(x) { quote(unquote(x) * 2) };
+
+			double(1); double(a + b);
+			`,
+			`1 * 2; (a + b) * 2`,
+		},
+		{
+			`
+			let twice = macro(x) { return quote(unquote(x) + unquote(x)) };
+
+			f(twice(y));
+			`,
+			`f((y + y))`,
+		},
+	}
+
+	for _, tt := range tests {
+		expected := testParseProgram(tt.expected)
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("macro expansion error: %s", err)
+		}
+
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let m = macro(x) { quote(x) };\nm(1, 2)", "2:2: wrong number of arguments to macro m. got=2, want=1"},
+		{"let m = macro() { 1 };\nm()", "2:2: macro m must return a quoted AST node, got INTEGER"},
+		{"let m = macro() { -true };\nm()", "2:2: macro m failed: unknown operator: -BOOLEAN"},
+	}
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Fatalf("expected macro expansion error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestMacrosEvaluation(t *testing.T) {
+	input := `
+	let unless = macro(condition, consequence, alternative) {
+		quote(if (!(unquote(condition))) { unquote(consequence) } else { unquote(alternative) });
+	};
+	let n = 0;
+	unless(n > 0, n += 10, n += 100);
+	n
+	`
+	program := testParseProgram(input)
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+	expanded, err := ExpandMacros(program, env)
+	if err != nil {
+		t.Fatalf("macro expansion error: %s", err)
+	}
+
+	testIntegerObject(t, Eval(expanded, object.NewEnvironment()), 10)
+
+	// unquote로 넣은 큰 정수, 배열, 해시도 원래 값으로 평가된다.
+	program = testParseProgram(`
+	let constants = macro() { quote(unquote({"big": 2 ** 100, "list": [1.5, "a"]})) };
+	let h = constants();
+	h["big"] == 2 ** 100 && h["list"] == [1.5, "a"]
+	`)
+	DefineMacros(program, env)
+	expanded, err = ExpandMacros(program, env)
+	if err != nil {
+		t.Fatalf("macro expansion error: %s", err)
+	}
+	testBooleanObject(t, Eval(expanded, object.NewEnvironment()), true)
+
+	evaluated := Eval(testParseProgram("let m = fn() { macro(x) { x } }; m()"), object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "macro literal must be bound by a top-level let statement" {
+		t.Errorf("expected macro literal error. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func testParseProgram(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
@@ -0,0 +1,152 @@
+package evaluator
+
+import (
+	"MonkeyKids/ast"
+	"MonkeyKids/object"
+	"MonkeyKids/token"
+	"fmt"
+	"math/big"
+)
+
+// 코드가 데이터고 데이터가 코드이다.
+// quote(<expression>)는 인수를 평가하지 않고 AST 노드 그대로 Quote 객체에 담아 돌려준다.
+// 인용된 코드 안의 unquote(<expression>) 호출은 인수를 평가한 결과를 다시 AST 노드로 바꿔 끼워 넣는다.
+func quote(node ast.Node, env *object.Environment) object.Object {
+	// 함수나 매크로 몸체는 여러 번 평가되므로 원래 트리를 건드리지 않도록 복사본을 바꾼다.
+	node, err := evalUnquoteCalls(ast.Copy(node), env)
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+
+func evalUnquoteCalls(quoted ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+
+	modified := ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if err != nil || !isUnquoteCall(node) {
+			return node
+		}
+		call := node.(*ast.CallExpression)
+		if len(call.Arguments) != 1 {
+			err = newError("wrong number of arguments to unquote. got=%d, want=1", len(call.Arguments))
+			err.Pos = call.Pos()
+			return node
+		}
+
+		unquoted := Eval(call.Arguments[0], env)
+		if e, ok := unquoted.(*object.Error); ok {
+			err = e
+			return node
+		}
+		converted, ok := convertObjectToASTNode(unquoted, map[object.Object]bool{})
+		if !ok {
+			err = newError("cannot unquote %s", unquoted.Type())
+			err.Pos = call.Pos()
+			return node
+		}
+		return converted
+	})
+	return modified, err
+}
+
+func isUnquoteCall(node ast.Node) bool {
+	call, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	return call.Function.TokenLiteral() == "unquote"
+}
+
+// unquote가 평가한 값을 인용된 코드에 넣을 수 있는 AST 노드로 바꾼다.
+// 리터럴로 쓸 수 있는 값과 Quote만 바꿀 수 있다.
+// 배열과 해시는 원소를 모두 바꿀 수 있을 때만 바꾼다. 자기 자신을 담은 배열이나 해시는 리터럴로 쓸 수 없다.
+func convertObjectToASTNode(obj object.Object, seen map[object.Object]bool) (ast.Node, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{Type: token.INT, Literal: fmt.Sprintf("%d", obj.Value)}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, true
+
+	case *object.BigInteger:
+		t := token.Token{Type: token.INT, Literal: obj.Value.String()}
+		return &ast.BigIntegerLiteral{Token: t, Value: new(big.Int).Set(obj.Value)}, true
+
+	// 2.0이 정수 2로 보이지 않도록 Inspect 결과를 리터럴로 쓴다.
+	case *object.Float:
+		t := token.Token{Type: token.FLOAT, Literal: obj.Inspect()}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}, true
+
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}, true
+
+	case *object.Boolean:
+		var t token.Token
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		} else {
+			t = token.Token{Type: token.FALSE, Literal: "false"}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}, true
+
+	case *object.Null:
+		return &ast.NullLiteral{Token: token.Token{Type: token.NULL, Literal: "null"}}, true
+
+	case *object.Array:
+		if seen[obj] {
+			return nil, false
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		elements := make([]ast.Expression, len(obj.Elements))
+		for i, el := range obj.Elements {
+			exp, ok := convertObjectToExpression(el, seen)
+			if !ok {
+				return nil, false
+			}
+			elements[i] = exp
+		}
+		t := token.Token{Type: token.LBRACKET, Literal: "["}
+		return &ast.ArrayLiteral{Token: t, Elements: elements}, true
+
+	case *object.Hash:
+		if seen[obj] {
+			return nil, false
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		var pairs []ast.HashPair
+		for _, pair := range obj.OrderedPairs() {
+			key, ok := convertObjectToExpression(pair.Key, seen)
+			if !ok {
+				return nil, false
+			}
+			value, ok := convertObjectToExpression(pair.Value, seen)
+			if !ok {
+				return nil, false
+			}
+			pairs = append(pairs, ast.HashPair{Key: key, Value: value})
+		}
+		t := token.Token{Type: token.LBRACE, Literal: "{"}
+		return &ast.HashLiteral{Token: t, Pairs: pairs}, true
+
+	// 같은 인수를 여러 번 넣어도 노드를 공유하지 않도록 복사한다.
+	case *object.Quote:
+		return ast.Copy(obj.Node), true
+
+	default:
+		return nil, false
+	}
+}
+
+// 배열과 해시의 원소 자리에는 표현식만 올 수 있다.
+func convertObjectToExpression(obj object.Object, seen map[object.Object]bool) (ast.Expression, bool) {
+	node, ok := convertObjectToASTNode(obj, seen)
+	if !ok {
+		return nil, false
+	}
+	exp, ok := node.(ast.Expression)
+	return exp, ok
+}
@@ -0,0 +1,100 @@
+package evaluator
+
+import (
+	"MonkeyKids/object"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(5 + 8)`, `(5 + 8)`},
+		{`quote(foobar)`, `foobar`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(a ?? b?.[0])`, `(a ?? (b?.[0]))`},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testQuoteObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(unquote(4))`, `4`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`quote(unquote(4 + 4) + 8)`, `(8 + 8)`},
+		{`let foobar = 8; quote(foobar)`, `foobar`},
+		{`let foobar = 8; quote(unquote(foobar))`, `8`},
+		{`quote(unquote(true))`, `true`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{`let quotedInfixExpression = quote(4 + 4);
+		quote(unquote(4 + 4) + unquote(quotedInfixExpression))`, `(8 + (4 + 4))`},
+		{`quote(unquote("monkey"))`, `monkey`},
+		{`quote(unquote(1.5))`, `1.5`},
+		{`quote(unquote(null))`, `null`},
+		{`quote(unquote(2 ** 100))`, `1267650600228229401496703205376`},
+		{`quote(unquote(2.0))`, `2.0`},
+		{`quote(unquote([1, "a", [2.5, null]]))`, `[1, a, [2.5, null]]`},
+		{`quote(unquote({"b": 1, "a": [true]}))`, `{b:1, a:[true]}`},
+		{`quote(unquote([quote(x + 1)]))`, `[(x + 1)]`},
+		{`let a = [1]; quote(unquote([a, a]))`, `[[1], [1]]`},
+		{`quote(f(unquote(1 + 1)))`, `f(2)`},
+		{`quote(fn(x) { x + unquote(2 * 3) })`, `fn(x)(x + 6)`},
+		// 함수 몸체 안의 quote는 호출할 때마다 unquote를 다시 평가한다.
+		{`let f = fn(x) { quote(unquote(x)) }; f(1); f(2)`, `2`},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testQuoteObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestQuoteUnquoteErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(1, 2)`, "wrong number of arguments to quote. got=2, want=1"},
+		{`quote(unquote(1, 2))`, "wrong number of arguments to unquote. got=2, want=1"},
+		{`quote(unquote(fn(x) { x }))`, "cannot unquote FUNCTION"},
+		{`quote(unquote([1, fn(x) { x }]))`, "cannot unquote ARRAY"},
+		{`quote(unquote({"f": len}))`, "cannot unquote HASH"},
+		{`let a = [1]; a[0] = a; quote(unquote(a))`, "cannot unquote ARRAY"},
+		{`quote(unquote(-true))`, "unknown operator: -BOOLEAN"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func testQuoteObject(t *testing.T, evaluated object.Object, expected string) {
+	t.Helper()
+
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		t.Fatalf("expected *object.Quote. got=%T (%+v)", evaluated, evaluated)
+	}
+	if quote.Node == nil {
+		t.Fatalf("quote.Node is nil")
+	}
+	if quote.Node.String() != expected {
+		t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), expected)
+	}
+}
@@ -7,44 +7,95 @@ package lexer
 // 렉서는 소스코드를 입력으로 받고 표헌하는 토큰열을 결과로 출력
 // 흝어가면서 토큰을 인식할 때마다 결과를 출력한다.
 // 버퍼도 필요업고 토큰을 저장할 필요 없다.
-// 상용버전에서는 파일 이름과 행번호를 토큰에 붙여, 렉싱에서 생긴 에러와 파싱에서 생긴 에러를 더 쉽게 추적
-import "MonkeyKids/token"
+// 상용버전처럼 파일 이름과 행, 열 번호를 토큰에 붙여, 렉싱에서 생긴 에러와 파싱에서 생긴 에러를 더 쉽게 추적
+import (
+	"MonkeyKids/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
-// ASCII 문자만 지원
-// 유니코드와 UTF8을 지원하기 위해서는 ch를 rune타입으로 바꾸고 다음 문자들을 읽는 방식을 바꿔야 한다.
-// 유니코드는 문자 하나에 여러개의 바이트가 할당
+// 유니코드(UTF-8) 지원
+// 유니코드는 문자 하나에 여러개의 바이트가 할당되기 때문에 ch를 rune 타입으로 두고, 한 문자씩 디코딩하면서 바이트 폭만큼 전진한다.
+// position, readPosition은 바이트 위치, column은 문자(코드 포인트) 단위의 열 번호
 type Lexer struct {
 	input string
 	// 입력 문자를 가리키는 포인터가 2개인 이유는 다음 처리 대상을 알아내려면 입력 문자열에서 다음 문자를 '미리 살펴봄' 과 동시에
 	// 현재 문자를 보존할 수 있어야 한다.
 	position     int  // 입력에서 현재 위치(현재 문자를 가리킴)
 	readPosition int  // 입력에서 현재 읽는 위치(현재 문자의 다음을 가리킴)
-	ch           byte // 현재 조사하고 있는 문자
+	ch           rune // 현재 조사하고 있는 문자
+
+	filename string // 토큰 위치에 붙일 파일 이름
+	line     int    // 현재 문자의 행 번호
+	column   int    // 현재 문자의 열 번호
+
+	// 자동 세미콜론 삽입
+	// 직전 토큰이 명령문을 끝낼 수 있는 토큰이면, 개행을 만났을 때 세미콜론 토큰을 만들어 낸다.
+	insertSemi bool
+	brackets   []byte // 아직 닫히지 않은 괄호들, 소괄호와 대괄호 안에서는 개행이 명령문을 끝내지 않는다. '$'는 문자열 보간의 ${
+
+	keepComments bool // 주석을 건너뛰지 않고 COMMENT 토큰으로 내보낼지 여부
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// 파일 이름을 토큰 위치에 같이 기록하는 렉서
+func NewWithFilename(filename string, input string) *Lexer {
+	l := New(input)
+	l.filename = filename
+	return l
+}
+
+// 주석을 COMMENT 토큰으로 내보내도록 설정한다.
+// 포매터나 문서 도구처럼 주석을 보존해야 하는 경우에 사용하며, 파서는 이 토큰들을 AST 노드에 붙인다.
+func (l *Lexer) KeepComments(keep bool) {
+	l.keepComments = keep
+}
+
 func (l *Lexer) readChar() {
+	// 직전 문자가 개행이면 다음 행으로 넘어간다.
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	width := 1
 	if l.readPosition >= len(l.input) {
 		// 만약 끝에 도달시 0을 삽입
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		// 잘못된 UTF-8 바이트는 utf8.RuneError가 되어 ILLEGAL 토큰으로 나온다.
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition // 항상 다음에 읽어야할  위치
-	l.readPosition += 1         // 항상 마지막으로 읶은 위치
+	l.readPosition += width     // 항상 마지막으로 읶은 위치
 }
 func (l *Lexer) NextToken() token.Token {
+	tok := l.readToken()
+	// 주석은 세미콜론 삽입에 영향을 주지 않는다.
+	if tok.Type == token.COMMENT {
+		return tok
+	}
+	l.insertSemi = endsStatement(tok.Type)
+	l.trackBrackets(tok.Type)
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
+	pos := l.currentPosition()
+
 	switch l.ch {
 	// 두문자 토큰을 case문 하나를 추가 하지 앟는 이유
-	// byte인 l.ch를 문자열인 "=="과 비교가 불가
+	// rune인 l.ch를 문자열인 "=="과 비교가 불가
 	case '=':
 		if l.peekChar() == '=' {
 			// readChar 호출전에 l.ch를 지역 변수에 저장
@@ -65,6 +116,20 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
+	case '&':
+		tok = l.newOperatorToken(token.BIT_AND, '&', token.AND)
+	case '|':
+		tok = l.newOperatorToken(token.BIT_OR, '|', token.OR)
+	case '?':
+		if l.peekChar() == '.' {
+			tok = l.newOperatorToken(token.ILLEGAL, '.', token.OPTIONAL_CHAIN)
+		} else {
+			tok = l.newOperatorToken(token.ILLEGAL, '?', token.NULLISH)
+		}
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case '(':
@@ -74,25 +139,51 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.newOperatorToken(token.PLUS, '=', token.PLUS_ASSIGN)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.newOperatorToken(token.MINUS, '=', token.MINUS_ASSIGN)
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		// 주석
+		if l.peekChar() == '/' || l.peekChar() == '*' {
+			tok = l.readComment()
+			tok.Pos = pos
+			if tok.Type == token.COMMENT && !l.keepComments {
+				return l.readToken()
+			}
+			return tok
+		}
+		tok = l.newOperatorToken(token.SLASH, '=', token.SLASH_ASSIGN)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '<' {
+			tok = l.newOperatorToken(token.LT, '<', token.SHIFT_LEFT)
+		} else {
+			tok = l.newOperatorToken(token.LT, '=', token.LT_EQ)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '>' {
+			tok = l.newOperatorToken(token.GT, '>', token.SHIFT_RIGHT)
+		} else {
+			tok = l.newOperatorToken(token.GT, '=', token.GT_EQ)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			tok = l.newOperatorToken(token.ASTERISK, '*', token.POWER)
+		} else {
+			tok = l.newOperatorToken(token.ASTERISK, '=', token.ASTERISK_ASSIGN)
+		}
 	case '{':
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
-		tok = newToken(token.RBRACE, l.ch)
+		// 문자열 보간을 닫는 }라면 문자열의 나머지를 이어서 읽는다.
+		if n := len(l.brackets); n > 0 && l.brackets[n-1] == '$' {
+			l.brackets = l.brackets[:n-1]
+			tok = l.readString(token.TEMPLATE_END)
+		} else {
+			tok = newToken(token.RBRACE, l.ch)
+		}
 		// 문자열
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok = l.readString(token.STRING)
 		// 배열
 		// 개별적으로 접근이 가능
 	case '[':
@@ -101,6 +192,13 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+		// skipWhitespace가 개행에서 멈췄다면 세미콜론을 넣을지 결정한다.
+	case '\n':
+		if l.continuesOnNextLine() {
+			l.insertSemi = false
+			return l.readToken()
+		}
+		tok = token.Token{Type: token.SEMICOLON, Literal: "\n"}
 
 	case 0:
 		tok.Literal = ""
@@ -109,23 +207,40 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok // 조기종료(꼭 필요)
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Pos = pos
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 	l.readChar()
+	tok.Pos = pos
 	return tok
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+// 현재 문자의 소스 위치
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{Filename: l.filename, Line: l.line, Column: l.column}
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
+// 다음 문자가 second면 두 글자 연산자(+=, <=, && 등) 토큰을, 아니면 한 글자 연산자 토큰을 만든다.
+func (l *Lexer) newOperatorToken(single token.TokenType, second rune, double token.TokenType) token.Token {
+	if l.peekChar() != second {
+		return newToken(single, l.ch)
+	}
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: double, Literal: string(ch) + string(l.ch)}
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isLetter(l.ch) {
@@ -134,44 +249,258 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// 한글 같은 유니코드 문자도 식별자에 쓸 수 있다.
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
+// 세미콜론을 넣어야 하는 상황이면 개행에서 멈춘다.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' || (l.ch == '\n' && !l.semicolonAtNewline()) {
+		l.readChar()
+	}
+}
+
+// 개행이 명령문을 끝낼 수 있는지 확인한다.
+// 가장 안쪽 괄호가 소괄호나 대괄호라면 표현식 중간이므로 개행을 무시한다.
+func (l *Lexer) semicolonAtNewline() bool {
+	if !l.insertSemi {
+		return false
+	}
+	if n := len(l.brackets); n > 0 && l.brackets[n-1] != '{' {
+		return false
+	}
+	return true
+}
+
+// 다음 행이 앞의 표현식을 이어가는지 확인한다.
+// 다음 토큰이 명령문을 시작할 수 없는 토큰(else, 닫는 괄호, 중위 연산자 등)이면 이어지는 것으로 본다.
+// 렉서를 복사해서 다음 토큰을 미리 읽어보기 때문에 원래 렉서의 상태는 바뀌지 않는다.
+func (l *Lexer) continuesOnNextLine() bool {
+	peek := *l
+	peek.insertSemi = false
+	peek.keepComments = false
+	peek.brackets = append([]byte(nil), l.brackets...)
+	next := peek.readToken()
+
+	switch next.Type {
+	case token.ELSE, token.RPAREN, token.RBRACKET, token.RBRACE, token.COMMA, token.COLON,
+		token.ASSIGN, token.PLUS_ASSIGN, token.MINUS_ASSIGN, token.ASTERISK_ASSIGN, token.SLASH_ASSIGN,
+		token.EQ, token.NOT_EQ, token.LT, token.GT, token.LT_EQ, token.GT_EQ, token.AND, token.OR,
+		token.PLUS, token.ASTERISK, token.SLASH, token.PERCENT, token.POWER,
+		token.BIT_AND, token.BIT_OR, token.BIT_XOR, token.SHIFT_LEFT, token.SHIFT_RIGHT,
+		token.NULLISH, token.OPTIONAL_CHAIN:
+		return true
+	}
+	return false
+}
+
+// 행 끝에 왔을 때 명령문을 끝낼 수 있는 토큰들
+func endsStatement(t token.TokenType) bool {
+	switch t {
+	case token.IDENT, token.INT, token.FLOAT, token.STRING, token.TEMPLATE_END, token.TRUE, token.FALSE, token.NULL, token.RETURN,
+		token.BREAK, token.CONTINUE, token.RPAREN, token.RBRACKET, token.RBRACE:
+		return true
+	}
+	return false
+}
+
+// 가장 안쪽부터 n개까지 닫히지 않은 소괄호와 대괄호를 잊는다.
+// 파서가 망가진 명령문을 버리고 다음 명령문에서 다시 시작할 때 호출한다.
+func (l *Lexer) DropOpenParens(n int) {
+	for ; n > 0; n-- {
+		last := len(l.brackets) - 1
+		if last < 0 || (l.brackets[last] != '(' && l.brackets[last] != '[') {
+			return
+		}
+		l.brackets = l.brackets[:last]
+	}
+}
+
+func (l *Lexer) trackBrackets(t token.TokenType) {
+	switch t {
+	case token.LPAREN:
+		l.brackets = append(l.brackets, '(')
+	case token.LBRACKET:
+		l.brackets = append(l.brackets, '[')
+	case token.LBRACE:
+		l.brackets = append(l.brackets, '{')
+	case token.RPAREN, token.RBRACKET, token.RBRACE:
+		if len(l.brackets) > 0 {
+			l.brackets = l.brackets[:len(l.brackets)-1]
+		}
+	}
+}
+
+// // 행 주석은 개행 직전까지, /* */ 블록 주석은 닫는 */까지 읽는다.
+// 행 주석 끝의 개행은 남겨 두기 때문에 주석 뒤에서도 세미콜론이 삽입된다.
+// 닫히지 않은 블록 주석은 ILLEGAL 토큰이 된다.
+func (l *Lexer) readComment() token.Token {
+	position := l.position
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		return token.Token{Type: token.COMMENT, Literal: l.input[position:l.position]}
+	}
+
+	l.readChar() // '*'
+	for {
 		l.readChar()
+		if l.ch == 0 {
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[position:l.position]}
+		}
+		if l.ch == '*' && l.peekChar() == '/' {
+			l.readChar()
+			l.readChar()
+			return token.Token{Type: token.COMMENT, Literal: l.input[position:l.position]}
+		}
 	}
 }
 
-func (l *Lexer) readNumber() string {
+// 소수점 아래 숫자나 지수가 붙으면 FLOAT, 아니면 INT
+// 점 뒤에 숫자가 없으면 소수점으로 보지 않는다.
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
+	tokenType := token.TokenType(token.INT)
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+	if l.ch == 'e' || l.ch == 'E' {
+		// 지수 부호 뒤에도 숫자가 와야 한다.
+		next := l.peekAt(1)
+		if isDigit(next) || (next == '+' || next == '-') && isDigit(l.peekAt(2)) {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			l.readDigits()
+		}
+	}
+	return tokenType, l.input[position:l.position]
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
 // readChar과 비슷,l.position과 l.readPosition을 증가시키지 않는다.
 //다음에 나올 입력을 미리 살펴보고 싶은 것
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition]
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+// 현재 문자에서 n 바이트 뒤의 문자, 숫자 리터럴처럼 ASCII만 확인할 때 사용
+func (l *Lexer) peekAt(n int) rune {
+	if l.position+n >= len(l.input) {
+		return 0
+	}
+	return rune(l.input[l.position+n])
+}
+
+// 여는 " 또는 보간을 닫는 } 다음부터 문자열을 읽는다.
+// 닫는 "에서 끝나면 endType 토큰, ${에서 멈추면 TEMPLATE 토큰이 되며 리터럴에는 이스케이프를 풀어낸 값이 들어간다.
+// 잘못된 이스케이프는 ILLEGAL 토큰이 되고, 리터럴은 문제가 된 이스케이프 시퀀스이다.
+func (l *Lexer) readString(endType token.TokenType) token.Token {
+	var out strings.Builder
+	illegal := ""
+
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		switch {
+		case l.ch == '"' || l.ch == 0:
+			if illegal != "" {
+				return token.Token{Type: token.ILLEGAL, Literal: illegal}
+			}
+			return token.Token{Type: endType, Literal: out.String()}
+
+		case l.ch == '$' && l.peekChar() == '{':
+			l.readChar()
+			if illegal != "" {
+				return token.Token{Type: token.ILLEGAL, Literal: illegal}
+			}
+			l.brackets = append(l.brackets, '$')
+			return token.Token{Type: token.TEMPLATE, Literal: out.String()}
+
+		case l.ch == '\\':
+			start := l.position
+			ch, ok := l.readEscape()
+			if !ok && illegal == "" {
+				// 입력 끝에서 끊긴 이스케이프는 readPosition이 입력 밖을 가리킨다.
+				end := l.readPosition
+				if end > len(l.input) {
+					end = len(l.input)
+				}
+				illegal = l.input[start:end]
+			}
+			out.WriteRune(ch)
+
+		default:
+			out.WriteRune(l.ch)
 		}
 	}
-	return l.input[position:l.position]
+}
+
+// \ 다음 문자를 읽어서 이스케이프 시퀀스가 나타내는 문자를 돌려준다.
+// \n \t \" \\ \$ 와 16진수 코드 포인트 \u{...} 를 지원한다.
+func (l *Lexer) readEscape() (rune, bool) {
+	l.readChar()
+	switch l.ch {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case '"', '\\', '$':
+		return l.ch, true
+	case 'u':
+		if l.peekChar() != '{' {
+			return utf8.RuneError, false
+		}
+		l.readChar()
+		var value rune
+		digits := 0
+		for l.peekChar() != '}' {
+			d := hexValue(l.peekChar())
+			if d < 0 || digits == 6 {
+				return utf8.RuneError, false
+			}
+			l.readChar()
+			value = value*16 + d
+			digits++
+		}
+		l.readChar()
+		if digits == 0 || !utf8.ValidRune(value) {
+			return utf8.RuneError, false
+		}
+		return value, true
+	}
+	return utf8.RuneError, false
+}
+
+func hexValue(ch rune) rune {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	case 'A' <= ch && ch <= 'F':
+		return ch - 'A' + 10
+	}
+	return -1
 }
@@ -91,3 +91,433 @@ func TestNextToken1(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPosition(t *testing.T) {
+	input := "let x = 5;\n  x + 10;"
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 2, 3},
+		{token.PLUS, 2, 5},
+		{token.INT, 2, 7},
+		{token.SEMICOLON, 2, 9},
+		{token.EOF, 2, 10},
+	}
+	l := NewWithFilename("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+		if tok.Pos.Filename != "test.mk" {
+			t.Fatalf("tests[%d] - filename wrong. got=%q", i, tok.Pos.Filename)
+		}
+	}
+}
+
+func TestAutomaticSemicolon(t *testing.T) {
+	input := `let x = 5
+let add = fn(a,
+	b) {
+	a
+	+ b
+}
+if (x) { x }
+else { [1,
+	2] }
+return
+`
+
+	tests := []testStruct{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, "\n"},
+		{token.LET, "let"},
+		{token.IDENT, "add"},
+		{token.ASSIGN, "="},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.IDENT, "b"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "a"},
+		{token.PLUS, "+"},
+		{token.IDENT, "b"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, "\n"},
+		{token.IF, "if"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.RBRACE, "}"},
+		{token.ELSE, "else"},
+		{token.LBRACE, "{"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, "\n"},
+		{token.RETURN, "return"},
+		{token.SEMICOLON, "\n"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// 잊은 소괄호와 대괄호 뒤에서는 개행이 다시 명령문을 끝낸다. 중괄호는 잊지 않는다.
+func TestDropOpenParens(t *testing.T) {
+	l := New("{[(1\n2\n3")
+	for _, expected := range []token.TokenType{token.LBRACE, token.LBRACKET, token.LPAREN, token.INT} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("tokenType wrong. expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+	l.DropOpenParens(5)
+
+	tests := []testStruct{
+		{token.SEMICOLON, "\n"},
+		{token.INT, "2"},
+		{token.SEMICOLON, "\n"},
+		{token.INT, "3"},
+		{token.EOF, ""},
+	}
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// 첫 줄 주석
+let x = 5 // 끝 주석
+/* 블록
+   주석 */ x / 2
+/* 닫히지 않은`
+
+	skipped := []testStruct{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, "\n"},
+		{token.ILLEGAL, "/* 닫히지 않은"},
+		{token.EOF, ""},
+	}
+	kept := []testStruct{
+		{token.COMMENT, "// 첫 줄 주석"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.COMMENT, "// 끝 주석"},
+		{token.SEMICOLON, "\n"},
+		{token.COMMENT, "/* 블록\n   주석 */"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, "\n"},
+		{token.ILLEGAL, "/* 닫히지 않은"},
+		{token.EOF, ""},
+	}
+
+	for _, keep := range []bool{false, true} {
+		tests := skipped
+		if keep {
+			tests = kept
+		}
+		l := New(input)
+		l.KeepComments(keep)
+
+		for i, tt := range tests {
+			tok := l.NextToken()
+			if tok.Type != tt.expectedType {
+				t.Fatalf("keep=%t tests[%d] - tokenType wrong. expected=%q, got=%q", keep, i, tt.expectedType, tok.Type)
+			}
+			if tok.Literal != tt.expectedLiteral {
+				t.Fatalf("keep=%t tests[%d] - Literal wrong. expected=%q, got=%q", keep, i, tt.expectedLiteral, tok.Literal)
+			}
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let 이름 = \"철수\";\nlen(이름)"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "이름", 5},
+		{token.ASSIGN, "=", 8},
+		{token.STRING, "철수", 10},
+		{token.SEMICOLON, ";", 14},
+		{token.IDENT, "len", 1},
+		{token.LPAREN, "(", 4},
+		{token.IDENT, "이름", 5},
+		{token.RPAREN, ")", 7},
+		{token.EOF, "", 8},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d", i, tt.expectedColumn, tok.Pos.Column)
+		}
+	}
+}
+
+func TestStringEscapesAndInterpolation(t *testing.T) {
+	input := `"a\"b\\c\n\t\u{1F600}" "Hi ${name}, ${ {"k": 1}["k"] }!" "\q" "${x}"`
+
+	tests := []testStruct{
+		{token.STRING, "a\"b\\c\n\t😀"},
+		{token.TEMPLATE, "Hi "},
+		{token.IDENT, "name"},
+		{token.TEMPLATE, ", "},
+		{token.LBRACE, "{"},
+		{token.STRING, "k"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "k"},
+		{token.RBRACKET, "]"},
+		{token.TEMPLATE_END, "!"},
+		{token.ILLEGAL, `\q`},
+		{token.TEMPLATE, ""},
+		{token.IDENT, "x"},
+		{token.TEMPLATE_END, ""},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// 입력 끝에서 끊긴 이스케이프는 ILLEGAL 토큰이 된다.
+func TestUnterminatedEscape(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{`"\`, `\`},
+		{`"ab\`, `\`},
+		{`"\u{41`, `\u{41`},
+		{`"\u{`, `\u{`},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.ILLEGAL || tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %q - wrong token. expected=ILLEGAL %q, got=%s %q", tt.input, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("input %q - expected EOF. got=%s %q", tt.input, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestAssignOperators(t *testing.T) {
+	input := `x += 1 -= 2 *= 3 /= 4 + - * / ==`
+
+	tests := []testStruct{
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "2"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "3"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "4"},
+		{token.PLUS, "+"},
+		{token.MINUS, "-"},
+		{token.ASTERISK, "*"},
+		{token.SLASH, "/"},
+		{token.EQ, "=="},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	input := `<= >= < > % ** *= * & | ^ << >>`
+
+	tests := []testStruct{
+		{token.LT_EQ, "<="},
+		{token.GT_EQ, ">="},
+		{token.LT, "<"},
+		{token.GT, ">"},
+		{token.PERCENT, "%"},
+		{token.POWER, "**"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.ASTERISK, "*"},
+		{token.BIT_AND, "&"},
+		{token.BIT_OR, "|"},
+		{token.BIT_XOR, "^"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	input := `a && b || c & d`
+
+	tests := []testStruct{
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.BIT_AND, "&"},
+		{token.IDENT, "d"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNullOperators(t *testing.T) {
+	input := `null ?? a?.[0] f?.(x) ?`
+
+	tests := []testStruct{
+		{token.NULL, "null"},
+		{token.NULLISH, "??"},
+		{token.IDENT, "a"},
+		{token.OPTIONAL_CHAIN, "?."},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.IDENT, "f"},
+		{token.OPTIONAL_CHAIN, "?."},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.ILLEGAL, "?"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `3.14 1e-3 2E+10 10 5.x 7e`
+
+	tests := []testStruct{
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-3"},
+		{token.FLOAT, "2E+10"},
+		{token.INT, "10"},
+		{token.INT, "5"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "x"},
+		{token.INT, "7"},
+		{token.IDENT, "e"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
@@ -16,6 +16,6 @@ func main() {
 	fmt.Printf("Hello %s ! This is the Monkey programming language!\n",
 		user.Username)
 	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stderr, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout)
 
 }
@@ -1,6 +1,13 @@
 package object
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
 
 var Builtins = []struct {
 	Name    string
@@ -10,15 +17,17 @@ var Builtins = []struct {
 		"len",
 		&Builtin{Fn: func(args ...Object) Object {
 			if len(args) != 1 {
-				return newError("Wrong number of arguments. gpot=%d, want=1", len(args))
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
 			switch arg := args[0].(type) {
 			case *Array:
 				return &Integer{Value: int64(len(arg.Elements))}
 			case *String:
-				return &Integer{Value: int64(len(arg.Value))}
+				return &Integer{Value: int64(arg.Len())}
+			case *Range:
+				return &Integer{Value: arg.Len()}
 			default:
-				return newError("arguments to 'len' not supported, got=%s", args[0].Type())
+				return newError("argument to 'len' not supported, got %s", args[0].Type())
 			}
 		}},
 	},
@@ -38,8 +47,15 @@ var Builtins = []struct {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
+			// 문자열은 첫 번째 문자
+			if str, ok := args[0].(*String); ok {
+				if ch := str.CharAt(0); ch != nil {
+					return ch
+				}
+				return nil
+			}
 			if args[0].Type() != ARRAY_OBJ {
-				return newError("argument to first must be ARRAY, got %s", args[0].Type())
+				return newError("argument to 'first' must be ARRAY or STRING, got %s", args[0].Type())
 			}
 			arr := args[0].(*Array)
 			if len(arr.Elements) > 0 {
@@ -53,8 +69,14 @@ var Builtins = []struct {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
+			if str, ok := args[0].(*String); ok {
+				if ch := str.CharAt(int64(str.Len() - 1)); ch != nil {
+					return ch
+				}
+				return nil
+			}
 			if args[0].Type() != ARRAY_OBJ {
-				return newError("argument to last must be ARRAY, got %s", args[0].Type())
+				return newError("argument to 'last' must be ARRAY or STRING, got %s", args[0].Type())
 			}
 			arr := args[0].(*Array)
 			length := len(arr.Elements)
@@ -68,8 +90,16 @@ var Builtins = []struct {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
+			// 문자열은 첫 번째 문자를 뺀 나머지 문자열
+			if str, ok := args[0].(*String); ok {
+				if str.Value == "" {
+					return nil
+				}
+				_, width := utf8.DecodeRuneInString(str.Value)
+				return &String{Value: str.Value[width:]}
+			}
 			if args[0].Type() != ARRAY_OBJ {
-				return newError("argument to rest must be ARRAY, got %s", args[0].Type())
+				return newError("argument to 'rest' must be ARRAY or STRING, got %s", args[0].Type())
 			}
 			arr := args[0].(*Array)
 			length := len(arr.Elements)
@@ -86,7 +116,7 @@ var Builtins = []struct {
 				return newError("wrong number of arguments. got=%d, want=2", len(args))
 			}
 			if args[0].Type() != ARRAY_OBJ {
-				return newError("argument to push must be ARRAY, got %s", args[0].Type())
+				return newError("argument to 'push' must be ARRAY, got %s", args[0].Type())
 			}
 			arr := args[0].(*Array)
 			length := len(arr.Elements)
@@ -97,6 +127,114 @@ var Builtins = []struct {
 
 			return &Array{Elements: newElements}
 		}}},
+	// 정수로 변환, 실수는 소수점 아래를 버린다.
+	{"int",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *Integer, *BigInteger:
+				return arg
+			case *Float:
+				if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+					return newError("cannot convert %s to INTEGER", arg.Inspect())
+				}
+				value, _ := big.NewFloat(arg.Value).Int(nil)
+				return NewInteger(value)
+			case *String:
+				value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 0)
+				if !ok {
+					return newError("could not parse %q as integer", arg.Value)
+				}
+				return NewInteger(value)
+			default:
+				return newError("argument to 'int' not supported, got %s", args[0].Type())
+			}
+		}}},
+	// 실수로 변환
+	{"float",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *Integer, *BigInteger:
+				value, _ := ToFloat(arg)
+				return &Float{Value: value}
+			case *Float:
+				return arg
+			case *String:
+				value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+				if err != nil {
+					return newError("could not parse %q as float", arg.Value)
+				}
+				return &Float{Value: value}
+			default:
+				return newError("argument to 'float' not supported, got %s", args[0].Type())
+			}
+		}}},
+	// range(끝), range(시작, 끝), range(시작, 끝, 간격)
+	// 끝은 범위에 들어가지 않는다.
+	{"range",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("wrong number of arguments. got=%d, want=1..3", len(args))
+			}
+			bounds := make([]int64, len(args))
+			for i, arg := range args {
+				integer, ok := arg.(*Integer)
+				if !ok {
+					return newError("argument to 'range' must be INTEGER, got %s", arg.Type())
+				}
+				bounds[i] = integer.Value
+			}
+
+			r := &Range{End: bounds[0], Step: 1}
+			if len(bounds) > 1 {
+				r.Start, r.End = bounds[0], bounds[1]
+			}
+			if len(bounds) > 2 {
+				r.Step = bounds[2]
+			}
+			if r.Step == 0 {
+				return newError("range step must not be zero")
+			}
+			return r
+		}}},
+	// 해시의 키와 값을 넣은 순서대로 배열에 담는다.
+	{"keys",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			hash, ok := args[0].(*Hash)
+			if !ok {
+				return newError("argument to 'keys' must be HASH, got %s", args[0].Type())
+			}
+			pairs := hash.OrderedPairs()
+			elements := make([]Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Key
+			}
+			return &Array{Elements: elements}
+		}}},
+	{"values",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			hash, ok := args[0].(*Hash)
+			if !ok {
+				return newError("argument to 'values' must be HASH, got %s", args[0].Type())
+			}
+			pairs := hash.OrderedPairs()
+			elements := make([]Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Value
+			}
+			return &Array{Elements: elements}
+		}}},
 }
 
 func newError(format string, a ...interface{}) *Error {
@@ -111,3 +249,10 @@ func GetBuiltinByName(name string) *Builtin {
 	}
 	return nil
 }
+
+// 내장 함수가 Inspect에서 자기 이름을 보여주도록 표의 이름을 채워 넣는다.
+func init() {
+	for _, def := range Builtins {
+		def.Builtin.Name = def.Name
+	}
+}
@@ -0,0 +1,117 @@
+package object
+
+import "strings"
+
+// 값 비교
+// 평가기와 가상 머신이 ==, !=, <, > 연산자를 같은 규칙으로 처리하도록 비교를 한 곳에서 정의한다.
+
+// 두 객체가 같은 값인지 비교한다.
+// 숫자는 타입이 달라도 값으로 비교하고(1 == 1.0), 문자열은 내용으로,
+// 배열은 길이와 원소로, 해시는 쌍으로 비교한다. 해시는 쌍의 순서와 상관없이 같은 키에 같은 값이 있으면 같다.
+// 함수처럼 값으로 비교할 수 없는 객체는 같은 객체일 때만 같다.
+func Equal(a, b Object) bool {
+	return equal(a, b, map[[2]Object]bool{})
+}
+
+// 자기 자신을 담은 배열이나 해시도 끝나도록, 비교 중인 컨테이너 쌍을 다시 만나면 같다고 본다.
+func equal(a, b Object, comparing map[[2]Object]bool) bool {
+	if isNumber(a) && isNumber(b) {
+		return numbersEqual(a, b)
+	}
+	if a == b {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		pair := [2]Object{a, b}
+		if comparing[pair] {
+			return true
+		}
+		comparing[pair] = true
+		defer delete(comparing, pair)
+
+		for i := range a.Elements {
+			if !equal(a.Elements[i], b.Elements[i], comparing) {
+				return false
+			}
+		}
+		return true
+
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		pair := [2]Object{a, b}
+		if comparing[pair] {
+			return true
+		}
+		comparing[pair] = true
+		defer delete(comparing, pair)
+
+		for _, p := range a.pairs {
+			other, ok := b.Get(p.Key.(Hashable))
+			if !ok || !equal(p.Value, other.Value, comparing) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return false
+	}
+}
+
+func isNumber(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInteger, *Float:
+		return true
+	}
+	return false
+}
+
+// 정수끼리는 정확하게, 실수가 섞이면 실수로 바꿔서 비교한다.
+func numbersEqual(a, b Object) bool {
+	if a, ok := a.(*Integer); ok {
+		if b, ok := b.(*Integer); ok {
+			return a.Value == b.Value
+		}
+	}
+	if x, ok := ToBigInt(a); ok {
+		if y, ok := ToBigInt(b); ok {
+			return x.Cmp(y) == 0
+		}
+	}
+	x, _ := ToFloat(a)
+	y, _ := ToFloat(b)
+	return x == y
+}
+
+// 두 객체의 순서를 비교해서 a < b면 -1, a == b면 0, a > b면 1을 돌려준다.
+// 지금은 문자열만 순서를 비교할 수 있고, 바이트 순서(사전 순서)로 비교한다. 비교할 수 없으면 false
+// 숫자의 대소 비교는 평가기와 가상 머신이 타입별로 따로 처리한다.
+func Compare(a, b Object) (int, bool) {
+	if a, ok := a.(*String); ok {
+		if b, ok := b.(*String); ok {
+			return strings.Compare(a.Value, b.Value), true
+		}
+	}
+	return 0, false
+}
@@ -33,6 +33,19 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// 이름이 정의된 가장 가까운 환경의 바인딩을 바꾼다.
+// Set과 달리 새 바인딩을 만들지 않으므로 클로저가 붙잡은 바깥 변수도 바꿀 수 있다.
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
+
 // 카운터 함수를 호출할 때 마다 많은 객체가 할당된다.
 // 객체는 메모리에 저장
 // 가비지 컬렉터는 매우 유용
@@ -0,0 +1,139 @@
+package object
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	RANGE_OBJ    = "RANGE"
+	ITERATOR_OBJ = "ITERATOR"
+)
+
+// for-in 반복문으로 순회할 수 있는 객체
+// 배열, 해시, 문자열, 범위가 구현한다.
+type Iterable interface {
+	Iter() *Iterator
+}
+
+// 반복자
+// 평가기와 가상 머신이 배열, 해시, 문자열, 범위를 같은 방식으로 순회하도록 한다.
+// 가상 머신은 반복문이 도는 동안 반복자를 스택에 올려 둔다.
+type Iterator struct {
+	next func() (key, value Object, ok bool)
+	// 변수 하나로 순회할 때 키를 넘기는지(해시) 값을 넘기는지(나머지)
+	keyIsElement bool
+}
+
+func (it *Iterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *Iterator) Inspect() string  { return "iterator" }
+
+// 변수 하나로 순회할 때 다음 요소
+// 배열은 원소, 문자열은 문자, 범위는 정수, 해시는 키를 준다.
+func (it *Iterator) Next() (Object, bool) {
+	key, value, ok := it.next()
+	if it.keyIsElement {
+		return key, ok
+	}
+	return value, ok
+}
+
+// 변수 둘로 순회할 때 다음 키와 값
+// 해시가 아니면 키는 0부터 시작하는 인덱스이다.
+func (it *Iterator) NextPair() (Object, Object, bool) {
+	return it.next()
+}
+
+// 순회할 수 없는 객체면 ok는 false
+func Iterate(obj Object) (*Iterator, bool) {
+	iterable, ok := obj.(Iterable)
+	if !ok {
+		return nil, false
+	}
+	return iterable.Iter(), true
+}
+
+// 배열은 순회하는 동안 바뀐 원소도 보인다.
+func (ao *Array) Iter() *Iterator {
+	index := 0
+	return &Iterator{next: func() (Object, Object, bool) {
+		if index >= len(ao.Elements) {
+			return nil, nil, false
+		}
+		key, value := &Integer{Value: int64(index)}, ao.Elements[index]
+		index++
+		return key, value, true
+	}}
+}
+
+// 해시는 반복자를 만들 때의 쌍을 키를 넣은 순서대로 순회한다.
+func (h *Hash) Iter() *Iterator {
+	pairs := h.OrderedPairs()
+
+	index := 0
+	return &Iterator{keyIsElement: true, next: func() (Object, Object, bool) {
+		if index >= len(pairs) {
+			return nil, nil, false
+		}
+		pair := pairs[index]
+		index++
+		return pair.Key, pair.Value, true
+	}}
+}
+
+// 문자열은 코드 포인트 단위로 순회한다.
+func (s *String) Iter() *Iterator {
+	offset, index := 0, 0
+	return &Iterator{next: func() (Object, Object, bool) {
+		if offset >= len(s.Value) {
+			return nil, nil, false
+		}
+		_, width := utf8.DecodeRuneInString(s.Value[offset:])
+		key := &Integer{Value: int64(index)}
+		value := &String{Value: s.Value[offset : offset+width]}
+		offset += width
+		index++
+		return key, value, true
+	}}
+}
+
+// 정수 범위 [Start, End)
+// range 내장 함수가 만들며 원소를 미리 만들어 두지 않는다.
+type Range struct {
+	Start int64
+	End   int64
+	Step  int64
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	if r.Step == 1 {
+		return fmt.Sprintf("range(%d, %d)", r.Start, r.End)
+	}
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+// 범위에 들어 있는 정수의 개수
+func (r *Range) Len() int64 {
+	if r.Step > 0 && r.Start < r.End {
+		return int64((uint64(r.End-r.Start)-1)/uint64(r.Step) + 1)
+	}
+	if r.Step < 0 && r.Start > r.End {
+		return int64((uint64(r.Start-r.End)-1)/uint64(-r.Step) + 1)
+	}
+	return 0
+}
+
+func (r *Range) Iter() *Iterator {
+	var index int64
+	length := r.Len()
+	return &Iterator{next: func() (Object, Object, bool) {
+		if index >= length {
+			return nil, nil, false
+		}
+		key := &Integer{Value: index}
+		value := &Integer{Value: r.Start + index*r.Step}
+		index++
+		return key, value, true
+	}}
+}
@@ -3,10 +3,16 @@ package object
 import (
 	"MonkeyKids/ast"
 	"MonkeyKids/code"
+	"MonkeyKids/token"
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // 트리 순회 평가기
@@ -16,9 +22,13 @@ import (
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
+	BIG_INTEGER_OBJ  = "BIG_INTEGER"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING" // 문자열로 표현하는것은 쉽다. Go언어가 가진 자료형을 재사용, 객체만 정의하면 된다.
@@ -32,6 +42,11 @@ const (
 
 	// 모든것을 클로저로
 	CLOSURE_OBJ = "CLOSURE"
+	CELL_OBJ    = "CELL"
+
+	// 코드를 데이터로
+	QUOTE_OBJ = "QUOTE"
+	MACRO_OBJ = "MACRO"
 )
 
 // 모든값을 Object 인터페이스를 만족하는 구조체로 감쌀 것이다.
@@ -54,6 +69,128 @@ func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
 func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
 
+// 큰 정수
+// int64 범위를 넘는 정수 연산 결과는 math/big으로 계산해서 BigInteger가 된다.
+// 결과가 다시 int64에 들어가면 NewInteger가 Integer로 되돌리기 때문에, BigInteger는 항상 int64 범위 밖의 값이다.
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (b *BigInteger) Type() ObjectType { return BIG_INTEGER_OBJ }
+func (b *BigInteger) Inspect() string  { return b.Value.String() }
+
+// big.Int를 정수 객체로 만든다. int64에 들어가면 Integer, 아니면 BigInteger
+func NewInteger(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &BigInteger{Value: value}
+}
+
+// Integer나 BigInteger를 big.Int로 바꾼다. 정수가 아니면 false
+func ToBigInt(obj Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value), true
+	case *BigInteger:
+		return obj.Value, true
+	}
+	return nil, false
+}
+
+// 넘침을 검사하는 int64 연산, 결과가 int64 범위를 벗어나면 false
+func AddInt64(a, b int64) (int64, bool) {
+	c := a + b
+	return c, (c > a) == (b > 0)
+}
+
+func SubInt64(a, b int64) (int64, bool) {
+	c := a - b
+	return c, (c < a) == (b > 0)
+}
+
+func MulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if (c < 0) != ((a < 0) != (b < 0)) || c/b != a {
+		return c, false
+	}
+	return c, true
+}
+
+// 거듭제곱과 왼쪽 시프트로 만들 수 있는 정수의 최대 비트 수
+// 2 ** 10000000000처럼 메모리를 다 쓸 계산은 시작하기 전에 에러를 낸다.
+const maxIntegerBits = 1 << 24
+
+// 정수 거듭제곱, 지수가 음수면 실수로 계산한다.
+func PowInteger(base, exp *big.Int) (Object, error) {
+	if exp.Sign() < 0 {
+		b, _ := new(big.Float).SetInt(base).Float64()
+		e, _ := new(big.Float).SetInt(exp).Float64()
+		return &Float{Value: math.Pow(b, e)}, nil
+	}
+	// 0, 1, -1은 지수가 아무리 커도 결과가 커지지 않는다.
+	if base.CmpAbs(big.NewInt(1)) > 0 {
+		if !exp.IsInt64() || exp.Int64() > maxIntegerBits/int64(base.BitLen()-1) {
+			return nil, fmt.Errorf("integer result too large")
+		}
+	}
+	return NewInteger(new(big.Int).Exp(base, exp, nil)), nil
+}
+
+// 정수 시프트, 오른쪽 시프트는 음수의 부호를 유지한다.
+func ShiftInteger(value, count *big.Int, left bool) (Object, error) {
+	if count.Sign() < 0 {
+		return nil, fmt.Errorf("negative shift count: %s", count)
+	}
+	if !left {
+		if !count.IsInt64() || count.Int64() > int64(value.BitLen()) {
+			count = big.NewInt(int64(value.BitLen()))
+		}
+		return NewInteger(new(big.Int).Rsh(value, uint(count.Int64()))), nil
+	}
+	if value.Sign() == 0 {
+		return &Integer{Value: 0}, nil
+	}
+	if !count.IsInt64() || count.Int64() > maxIntegerBits-int64(value.BitLen()) {
+		return nil, fmt.Errorf("integer result too large")
+	}
+	return NewInteger(new(big.Int).Lsh(value, uint(count.Int64()))), nil
+}
+
+// 실수
+// 정수와 실수를 섞어 계산하면 정수를 실수로 바꿔서 계산한다.
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// 정수와 구별되도록 소수점이 없으면 .0을 붙인다.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// 정수나 실수를 float64로 바꾼다. 숫자가 아니면 false
+func ToFloat(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *BigInteger:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f, true
+	case *Float:
+		return obj.Value, true
+	}
+	return 0, false
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -65,6 +202,7 @@ type Null struct{}
 
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 func (n *Null) Inspect() string  { return "null" }
+func (n *Null) HashKey() HashKey { return HashKey{Type: n.Type()} }
 
 // 평가하는 도중에 return 문을 만나면, 원래 반환해야 할 값을 객체 하나로 감싸서 처리한다.
 // 그래야만 평가기가 이 객체를 추적 가능: 평가 도중에 평가를 계속 해야할지 말지 결정할 때,
@@ -77,42 +215,111 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// break와 continue
+// 평가기에서 ReturnValue처럼 블록들을 빠져나가다가 가장 가까운 반복문에서 처리된다.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
 // 예외 처리
 type Error struct {
 	Message string
+	Pos     token.Position // 에러가 발생한 소스 위치
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 
 // 상용 인터츠리터라면 스택 트레이스를 담
 // 문제가 발생한 지점의 행과열 번호를 같이 넣어서 단순한 메시지보다 더 많은 정보를 줄 수도 있다.
-// 렉서가 토큰에 행과 열번호를 달아놨으면 어렵지 않다.
-func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+// 렉서가 토큰에 행과 열번호를 달아놨기 때문에 어렵지 않다.
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
 
 // Env가 있는 이유: 함수는 자기환경에서 움직이기 때문에
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string         // let으로 바인딩한 함수의 이름, 익명 함수면 빈 문자열
+	Pos        token.Position // 함수 리터럴이 시작하는 위치
+	End        token.Position // 함수 리터럴이 끝나는 위치
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
-	var out bytes.Buffer
+	return inspectFunction("fn", f.Name, identifierNames(f.Parameters))
+}
 
-	var params []string
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
+// 에러 메시지에서 함수를 가리키는 말
+func (f *Function) Describe() string {
+	return describeFunction(f.Name, identifierNames(f.Parameters), f.Pos, f.End)
+}
+
+// 함수는 몸체 대신 이름과 파라미터만 보여준다. 예) <fn fib(n)>, 익명 함수면 <fn(x, y)>
+func inspectFunction(kind, name string, params []string) string {
+	if name != "" {
+		kind += " " + name
 	}
+	return "<" + kind + "(" + strings.Join(params, ", ") + ")>"
+}
 
-	out.WriteString("fn")
-	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") {\n")
-	out.WriteString(f.Body.String())
-	out.WriteString("\n}")
+// 이름과 파라미터, 정의된 소스 범위로 함수를 가리킨다. 예) fib(n) defined at 1:11-3:1
+// 익명 함수는 fn(x)로, 위치를 모르는 함수(매크로가 만든 함수 등)는 위치 없이 쓴다.
+func describeFunction(name string, params []string, pos, end token.Position) string {
+	if name == "" {
+		name = "fn"
+	}
+	description := name + "(" + strings.Join(params, ", ") + ")"
+	if !pos.IsValid() {
+		return description
+	}
+	description += " defined at " + pos.String()
+	if end.IsValid() {
+		description += fmt.Sprintf("-%d:%d", end.Line, end.Column)
+	}
+	return description
+}
 
-	return out.String()
+func identifierNames(identifiers []*ast.Identifier) []string {
+	names := make([]string, len(identifiers))
+	for i, ident := range identifiers {
+		names[i] = ident.Value
+	}
+	return names
+}
+
+// 인용된 코드
+// quote(<expression>)는 표현식을 평가하지 않고 AST 노드 그대로 감싸서 돌려준다.
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+// 매크로
+// 함수와 같은 모양이지만 인수를 평가하지 않고 Quote로 받는다.
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	return inspectFunction("macro", "", identifierNames(m.Parameters))
 }
 
 type String struct {
@@ -122,28 +329,67 @@ type String struct {
 func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
 
+// 값을 문자열로 바꾼다. 문자열은 그대로 두고 나머지는 Inspect 결과를 사용
+// 문자열 보간에서 가상 머신과 평가기가 같은 결과를 내도록 함께 사용한다.
+func ToString(obj Object) *String {
+	if str, ok := obj.(*String); ok {
+		return str
+	}
+	return &String{Value: obj.Inspect()}
+}
+
+// 문자열의 길이, 바이트 수가 아니라 문자(코드 포인트) 수
+func (s *String) Len() int { return utf8.RuneCountInString(s.Value) }
+
+// i번째 문자를 담은 문자열, 범위를 벗어나면 nil
+func (s *String) CharAt(i int64) *String {
+	if i < 0 {
+		return nil
+	}
+	for _, ch := range s.Value {
+		if i == 0 {
+			return &String{Value: string(ch)}
+		}
+		i--
+	}
+	return nil
+}
+
 type BuiltinFunction func(args ...Object) Object
 
 // 내장 함수
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn   BuiltinFunction
+	Name string
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
-func (b *Builtin) Inspect() string  { return "builtin function" }
+func (b *Builtin) Inspect() string  { return "<builtin " + b.Name + ">" }
 
 // 배열 리터럴
 type Array struct {
 	Elements []Object
+	// 해시의 키로 들어간 배열은 얼려서 바꿀 수 없게 한다.
+	// 키가 바뀌면 해시 값이 달라져서 다시 찾을 수 없기 때문이다.
+	Frozen bool
 }
 
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
-func (ao *Array) Inspect() string {
+func (ao *Array) Inspect() string  { return ao.inspect(map[Object]bool{}) }
+
+// 자기 자신을 담은 배열은 안쪽의 자신을 [...]로 출력한다.
+func (ao *Array) inspect(seen map[Object]bool) string {
+	if seen[ao] {
+		return "[...]"
+	}
+	seen[ao] = true
+	defer delete(seen, ao)
+
 	var out bytes.Buffer
 
 	var elements []string
 	for _, e := range ao.Elements {
-		elements = append(elements, e.Inspect())
+		elements = append(elements, inspect(e, seen))
 	}
 
 	out.WriteString("[")
@@ -173,6 +419,16 @@ type HashKey struct {
 // 오픈 어드레싱(open addressing)
 // 버킷 하나에 엔트리 하나를 넣는다. 엔트리를 버킷에 넗으려 할때, 이미 버킷에 채워져 있다면 빈 버킷을 찾아서 넣는다.
 // 빈 버킷을 찾는 전략에 따라 구현체가 달라진다.
+//======================================================================================================================
+// Hash는 체이닝을 사용한다. HashKey가 같아도 Equal로 키를 비교해서 다른 키면 같은 버킷에 따로 담는다.
+
+// 문자열과 배열의 바이트를 해시 값으로 바꾸는 함수
+// 테스트에서 해시 충돌을 일부러 일으킬 때 바꿔 끼운다.
+var HashBytes = func(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
 
 func (b *Boolean) HashKey() HashKey {
 	var value uint64
@@ -184,15 +440,100 @@ func (b *Boolean) HashKey() HashKey {
 	}
 	return HashKey{Type: b.Type(), Value: value}
 }
+
+// 숫자는 Equal처럼 타입과 상관없이 값으로 비교하므로(1 == 1.0) 모두 실수로 바꾼 값을 해시한다.
+// 같은 실수가 되는 서로 다른 큰 정수는 같은 버킷에 들어가고 Equal로 구별한다.
 func (i *Integer) HashKey() HashKey {
-	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+	return numberHashKey(float64(i.Value))
+}
+
+func (b *BigInteger) HashKey() HashKey {
+	f, _ := ToFloat(b)
+	return numberHashKey(f)
+}
+
+// NaN은 자기 자신과도 같지 않아서 다시 찾을 수 없으므로 ToHashable이 키로 받지 않는다.
+func (f *Float) HashKey() HashKey {
+	return numberHashKey(f.Value)
+}
+
+// 0.0과 -0.0은 같은 키
+func numberHashKey(value float64) HashKey {
+	if value == 0 {
+		value = 0
+	}
+	return HashKey{Type: FLOAT_OBJ, Value: math.Float64bits(value)}
 }
 
 func (s *String) HashKey() HashKey {
-	h := fnv.New64a()
-	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: HashBytes([]byte(s.Value))}
+}
+
+// 원소들의 HashKey를 이어 붙여서 해시한다. 같은 원소를 같은 순서로 담은 배열은 같은 키
+// 원소의 타입 대신 HashKey의 타입을 쓰므로 [1]과 [1.0]도 같은 키
+// 키로 쓸 수 없는 원소가 있는지는 ToHashable이 미리 확인한다.
+func (ao *Array) HashKey() HashKey {
+	var data []byte
+	for _, e := range ao.Elements {
+		if e, ok := e.(Hashable); ok {
+			key := e.HashKey()
+			var value [8]byte
+			binary.LittleEndian.PutUint64(value[:], key.Value)
+			data = append(data, key.Type...)
+			data = append(data, value[:]...)
+		}
+	}
+	return HashKey{Type: ao.Type(), Value: HashBytes(data)}
+}
+
+// 객체를 해시의 키로 쓸 수 있으면 Hashable로 바꾼다.
+// 배열은 모든 원소를 키로 쓸 수 있어야 하고, 자기 자신을 담은 배열은 키로 쓸 수 없다.
+// NaN은 어떤 값과도 같지 않아서 넣은 쌍을 다시 찾을 수 없으므로 키로 쓸 수 없다.
+func ToHashable(obj Object) (Hashable, bool) {
+	if !isHashable(obj, map[Object]bool{}) {
+		return nil, false
+	}
+	return obj.(Hashable), true
+}
 
-	return HashKey{Type: s.Type(), Value: h.Sum64()}
+func isHashable(obj Object, visiting map[Object]bool) bool {
+	if f, ok := obj.(*Float); ok && math.IsNaN(f.Value) {
+		return false
+	}
+	array, ok := obj.(*Array)
+	if !ok {
+		_, ok := obj.(Hashable)
+		return ok
+	}
+	if visiting[array] {
+		return false
+	}
+	visiting[array] = true
+	defer delete(visiting, array)
+
+	for _, e := range array.Elements {
+		if !isHashable(e, visiting) {
+			return false
+		}
+	}
+	return true
+}
+
+// 키로 넣을 배열을 얼린 복사본으로 바꾼다. 원래 배열은 계속 바꿀 수 있다.
+func freezeKey(key Hashable) Hashable {
+	array, ok := key.(*Array)
+	if !ok || array.Frozen {
+		return key
+	}
+	elements := make([]Object, len(array.Elements))
+	for i, e := range array.Elements {
+		if e, ok := e.(*Array); ok {
+			elements[i] = freezeKey(e).(*Array)
+			continue
+		}
+		elements[i] = e
+	}
+	return &Array{Elements: elements, Frozen: true}
 }
 
 type HashPair struct {
@@ -200,19 +541,75 @@ type HashPair struct {
 	Value Object
 }
 
+// 해시는 키를 넣은 순서를 기억해서 Inspect와 for-in 순회가 언제나 같은 순서로 쌍을 내놓는다.
+// 해시 충돌은 체이닝으로 해결한다. HashKey가 같은 쌍들은 같은 버킷에 들어가고,
+// 버킷 안에서는 키의 실제 값을 비교해서 쌍을 찾는다.
+// 빈 Hash{}도 바로 사용할 수 있다.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	buckets map[HashKey][]int // HashKey가 같은 쌍들의 pairs 인덱스
+	pairs   []HashPair        // 키를 처음 넣은 순서
+}
+
+// 키에 해당하는 쌍을 찾는다.
+func (h *Hash) Get(key Hashable) (HashPair, bool) {
+	if i, ok := h.find(key); ok {
+		return h.pairs[i], true
+	}
+	return HashPair{}, false
+}
+
+// 쌍을 넣는다. 이미 있는 키면 값만 바꾸고 순서는 처음 넣었을 때 그대로 둔다.
+func (h *Hash) Set(key Hashable, value Object) {
+	if i, ok := h.find(key); ok {
+		h.pairs[i].Value = value
+		return
+	}
+	if h.buckets == nil {
+		h.buckets = make(map[HashKey][]int)
+	}
+	key = freezeKey(key)
+	hashKey := key.HashKey()
+	h.buckets[hashKey] = append(h.buckets[hashKey], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+func (h *Hash) find(key Hashable) (int, bool) {
+	for _, i := range h.buckets[key.HashKey()] {
+		if Equal(h.pairs[i].Key, key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// 해시에 든 쌍의 개수
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// 키를 넣은 순서대로 쌍을 돌려준다.
+func (h *Hash) OrderedPairs() []HashPair {
+	pairs := make([]HashPair, len(h.pairs))
+	copy(pairs, h.pairs)
+	return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
-func (h *Hash) Inspect() string {
+func (h *Hash) Inspect() string  { return h.inspect(map[Object]bool{}) }
+
+// 자기 자신을 담은 해시는 안쪽의 자신을 {...}로 출력한다.
+func (h *Hash) inspect(seen map[Object]bool) string {
+	if seen[h] {
+		return "{...}"
+	}
+	seen[h] = true
+	defer delete(seen, h)
+
 	var out bytes.Buffer
 
 	var pairs []string
 
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(),
-			pair.Value.Inspect()))
+	for _, pair := range h.OrderedPairs() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", inspect(pair.Key, seen),
+			inspect(pair.Value, seen)))
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -221,31 +618,68 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// 배열과 해시는 서로를 담을 수 있으므로 지금까지 출력 중인 컨테이너를 넘겨 순환을 끊는다.
+func inspect(obj Object, seen map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.inspect(seen)
+	case *Hash:
+		return obj.inspect(seen)
+	// 배열과 해시 안의 문자열은 따옴표로 감싸야 ["a, b"]와 ["a", "b"]가 구별된다.
+	case *String:
+		return quoteString(obj.Value)
+	default:
+		return obj.Inspect()
+	}
+}
+
+// 해시의 키로 쓸 수 있는 객체
+// 배열은 원소에 따라 키로 쓸 수 없기도 하므로 타입 단언 대신 ToHashable로 확인한다.
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
 type CompiledFunction struct {
 	Instructions  code.Instructions
-	NumLocals     int // 가상머신에게 이 함수 안에서 정의될 지역 변수가 몇 개인지 알려눚다.
-	NumParameters int // 현재 처리하고 있는 함수 리터럴이 갖는 파라미터 개수를 넣는다.
+	NumLocals     int            // 가상머신에게 이 함수 안에서 정의될 지역 변수가 몇 개인지 알려눚다.
+	NumParameters int            // 현재 처리하고 있는 함수 리터럴이 갖는 파라미터 개수를 넣는다.
+	SourceMap     code.SourceMap // 에러 위치를 알려주기 위한 명령어별 소스 위치
+	Name          string         // let으로 바인딩한 함수의 이름, 익명 함수면 빈 문자열
+	Parameters    []string       // 파라미터 이름, Inspect에서 보여준다.
+	Pos           token.Position // 함수 리터럴이 시작하는 위치
+	End           token.Position // 함수 리터럴이 끝나는 위치
 }
 
 func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
 func (cf *CompiledFunction) Inspect() string {
-	return fmt.Sprintf("compiledFunction[%p]", cf)
+	return inspectFunction("fn", cf.Name, cf.Parameters)
+}
+
+// 에러 메시지에서 함수를 가리키는 말
+func (cf *CompiledFunction) Describe() string {
+	return describeFunction(cf.Name, cf.Parameters, cf.Pos, cf.End)
 }
 
 // 가상 머신과 컴파일러로 클로져를 구현하는 것은 자유변수를 어떻게 처리하느냐에 달림
 // 컴파일러는 이런 자유변수 참조를 감지해 자유변수를 스택에 올리는 명령어를 배출해야 한다.
 // 함수를 컴파일할 때 자유번수 참조를 감지하고, 참조된 자유 변수값을 스택에 올리고, 컴파일된 함수와
 // 자유변수값을 병합해 클로져로 만들고 호출될 수 있도록 스택에 넣는다.
+// 자유 변수는 값이 아니라 셀로 붙잡는다. 그래야 대입한 값을 바깥 함수와 다른 클로저도 볼 수 있다.
 type Closure struct {
 	Fn   *CompiledFunction
-	Free []Object
+	Free []*Cell
 }
 
 func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
-func (c *Closure) Inspect() string {
-	return fmt.Sprintf("Closure[%p]", c)
+func (c *Closure) Inspect() string  { return c.Fn.Inspect() }
+
+// 클로저가 붙잡은 변수를 담는 힙 셀
+// 가상 머신은 지역 변수가 클로저에 붙잡히는 순간 스택 슬롯의 값을 셀로 바꿔 넣고,
+// 그 뒤로 슬롯과 클로저가 같은 셀을 통해 값을 읽고 쓴다.
+type Cell struct {
+	Value Object
 }
+
+func (c *Cell) Type() ObjectType { return CELL_OBJ }
+func (c *Cell) Inspect() string  { return c.Value.Inspect() }
@@ -1,6 +1,11 @@
 package object
 
-import "testing"
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -14,7 +19,384 @@ func TestStringHashKey(t *testing.T) {
 	if diff1.HashKey() != diff2.HashKey() {
 		t.Errorf("strings with same content have different hash keys")
 	}
-	if hello1.HashKey() != diff1.HashKey() {
-		t.Errorf("strings with same content have different hash keys")
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{3, "3.0"},
+		{-0.5, "-0.5"},
+		{1e21, "1e+21"},
+	}
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("Inspect wrong. want=%q, got=%q", tt.expected, f.Inspect())
+		}
+	}
+
+	if (&Float{Value: 0}).HashKey() != (&Float{Value: -0.0 * 1}).HashKey() {
+		t.Errorf("0.0 and -0.0 have different hash keys")
+	}
+	if (&Float{Value: 1.5}).HashKey() == (&Float{Value: 2.5}).HashKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+
+	// Equal이 같다고 보는 숫자는 타입이 달라도 같은 키
+	if (&Float{Value: 1}).HashKey() != (&Integer{Value: 1}).HashKey() {
+		t.Errorf("1.0 and 1 have different hash keys")
+	}
+	huge := new(big.Int).Lsh(big.NewInt(1), 70)
+	if (&Float{Value: math.Ldexp(1, 70)}).HashKey() != (&BigInteger{Value: huge}).HashKey() {
+		t.Errorf("2.0 ** 70 and 2 ** 70 have different hash keys")
+	}
+	if _, ok := ToHashable(&Float{Value: math.NaN()}); ok {
+		t.Errorf("NaN should not be hashable")
+	}
+}
+
+func TestCheckedIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		fn       func(a, b int64) (int64, bool)
+		a, b     int64
+		expected bool
+	}{
+		{AddInt64, math.MaxInt64, 1, false},
+		{AddInt64, math.MinInt64, -1, false},
+		{AddInt64, math.MaxInt64, -1, true},
+		{SubInt64, math.MinInt64, 1, false},
+		{SubInt64, 0, math.MinInt64, false},
+		{SubInt64, -1, math.MinInt64, true},
+		{MulInt64, math.MaxInt64, 2, false},
+		{MulInt64, math.MinInt64, -1, false},
+		{MulInt64, -1, math.MinInt64, false},
+		{MulInt64, math.MinInt64, 1, true},
+		{MulInt64, 1 << 31, 1 << 31, true},
+		{MulInt64, 1 << 32, 1 << 31, false},
+	}
+	for i, tt := range tests {
+		if _, ok := tt.fn(tt.a, tt.b); ok != tt.expected {
+			t.Errorf("tests[%d] - overflow check wrong for %d, %d. want ok=%t", i, tt.a, tt.b, tt.expected)
+		}
+	}
+}
+
+func TestBigInteger(t *testing.T) {
+	small := NewInteger(big.NewInt(42))
+	if _, ok := small.(*Integer); !ok {
+		t.Fatalf("value that fits int64 was not demoted. got=%T", small)
+	}
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	big1 := NewInteger(huge).(*BigInteger)
+	big2 := NewInteger(new(big.Int).Set(huge)).(*BigInteger)
+	neg := NewInteger(new(big.Int).Neg(huge)).(*BigInteger)
+
+	if big1.Inspect() != "123456789012345678901234567890" {
+		t.Errorf("Inspect wrong. got=%q", big1.Inspect())
+	}
+	if big1.HashKey() != big2.HashKey() {
+		t.Errorf("big integers with same value have different hash keys")
+	}
+	if big1.HashKey() == neg.HashKey() {
+		t.Errorf("big integers with different sign have same hash keys")
+	}
+}
+
+func TestPowAndShiftInteger(t *testing.T) {
+	tests := []struct {
+		fn       func(a, b *big.Int) (Object, error)
+		a, b     int64
+		expected string
+	}{
+		{PowInteger, 2, 10, "1024"},
+		{PowInteger, 2, 100, "1267650600228229401496703205376"},
+		{PowInteger, -1, math.MaxInt64, "-1"},
+		{PowInteger, 2, -2, "0.25"},
+		{PowInteger, 2, math.MaxInt64, "integer result too large"},
+		{shiftLeft, 1, 64, "18446744073709551616"},
+		{shiftLeft, 0, math.MaxInt64, "0"},
+		{shiftLeft, 1, math.MaxInt64, "integer result too large"},
+		{shiftLeft, 1, -1, "negative shift count: -1"},
+		{shiftRight, -5, math.MaxInt64, "-1"},
+		{shiftRight, 5, math.MaxInt64, "0"},
+	}
+	for i, tt := range tests {
+		result, err := tt.fn(big.NewInt(tt.a), big.NewInt(tt.b))
+		got := ""
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = result.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("tests[%d] - wrong result for %d, %d. want=%q, got=%q", i, tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func shiftLeft(value, count *big.Int) (Object, error) { return ShiftInteger(value, count, true) }
+
+func shiftRight(value, count *big.Int) (Object, error) { return ShiftInteger(value, count, false) }
+
+func TestIterators(t *testing.T) {
+	tests := []struct {
+		iterable Iterable
+		keys     []string
+		values   []string
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 5}, &String{Value: "a"}}}, []string{"0", "1"}, []string{"5", "a"}},
+		{&String{Value: "한a"}, []string{"0", "1"}, []string{"한", "a"}},
+		{&Range{Start: 0, End: 5, Step: 2}, []string{"0", "1", "2"}, []string{"0", "2", "4"}},
+		{&Range{Start: 3, End: 0, Step: -1}, []string{"0", "1", "2"}, []string{"3", "2", "1"}},
+		{&Range{Start: 0, End: 0, Step: 1}, []string{}, []string{}},
+		{&Range{Start: math.MinInt64, End: math.MaxInt64, Step: math.MaxInt64}, []string{"0", "1", "2"},
+			[]string{"-9223372036854775808", "-1", "9223372036854775806"}},
+	}
+	for _, tt := range tests {
+		iterator := tt.iterable.Iter()
+		var keys, values []string
+		for {
+			key, value, ok := iterator.NextPair()
+			if !ok {
+				break
+			}
+			keys = append(keys, key.Inspect())
+			values = append(values, value.Inspect())
+		}
+		if fmt.Sprint(keys) != fmt.Sprint(tt.keys) || fmt.Sprint(values) != fmt.Sprint(tt.values) {
+			t.Errorf("wrong iteration. want=%v %v, got=%v %v", tt.keys, tt.values, keys, values)
+		}
+	}
+
+	key := &String{Value: "k"}
+	hash := &Hash{}
+	hash.Set(key, &Integer{Value: 1})
+	element, ok := hash.Iter().Next()
+	if !ok || element != key {
+		t.Errorf("hash iteration with one variable should give the key. got=%v", element)
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	hash := &Hash{}
+	for i, name := range []string{"b", "a", "c", "a"} {
+		key := &String{Value: name}
+		hash.Set(key, &Integer{Value: int64(i)})
+	}
+
+	// 이미 있는 키에 다시 넣으면 값만 바뀌고 자리는 그대로이다.
+	if got := hash.Inspect(); got != `{"b": 0, "a": 3, "c": 2}` {
+		t.Errorf("hash has wrong Inspect. got=%q", got)
+	}
+
+	var keys []string
+	iterator := hash.Iter()
+	for {
+		key, ok := iterator.Next()
+		if !ok {
+			break
+		}
+		keys = append(keys, key.Inspect())
+	}
+	if fmt.Sprint(keys) != "[b a c]" {
+		t.Errorf("hash iterated in wrong order. got=%v", keys)
+	}
+}
+
+func TestHashCollisions(t *testing.T) {
+	hashBytes := HashBytes
+	HashBytes = func([]byte) uint64 { return 0 }
+	defer func() { HashBytes = hashBytes }()
+
+	a, b := &String{Value: "a"}, &String{Value: "b"}
+	if a.HashKey() != b.HashKey() {
+		t.Fatalf("HashBytes hook was not used")
+	}
+
+	hash := &Hash{}
+	hash.Set(a, &Integer{Value: 1})
+	hash.Set(b, &Integer{Value: 2})
+	hash.Set(&String{Value: "a"}, &Integer{Value: 3})
+
+	if hash.Len() != 2 {
+		t.Fatalf("hash has wrong number of pairs. got=%d", hash.Len())
+	}
+	for key, expected := range map[string]string{"a": "3", "b": "2"} {
+		pair, ok := hash.Get(&String{Value: key})
+		if !ok {
+			t.Errorf("no pair for key %q", key)
+			continue
+		}
+		if pair.Value.Inspect() != expected {
+			t.Errorf("wrong value for key %q. want=%s, got=%s", key, expected, pair.Value.Inspect())
+		}
+	}
+	if _, ok := hash.Get(&String{Value: "c"}); ok {
+		t.Errorf("key %q should not be found", "c")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a, b := &String{Value: "a"}, &String{Value: "b"}
+	left, right := &Hash{}, &Hash{}
+	left.Set(a, &Integer{Value: 1})
+	left.Set(b, &Array{Elements: []Object{&Float{Value: 2}}})
+	right.Set(&String{Value: "b"}, &Array{Elements: []Object{&Integer{Value: 2}}})
+	right.Set(&String{Value: "a"}, &Integer{Value: 1})
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Float{Value: 1}, true},
+		{&Float{Value: math.NaN()}, &Float{Value: math.NaN()}, false},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&String{Value: "1"}, &Integer{Value: 1}, false},
+		{&Null{}, &Null{}, true},
+		{left, right, true},
+		{left, &Hash{}, false},
+		{&Array{}, &Hash{}, false},
+	}
+	for i, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.expected {
+			t.Errorf("tests[%d] - Equal(%s, %s) wrong. want=%t, got=%t", i, tt.a.Inspect(), tt.b.Inspect(), tt.expected, got)
+		}
+	}
+
+	if cmp, ok := Compare(&String{Value: "a"}, &String{Value: "b"}); !ok || cmp >= 0 {
+		t.Errorf("\"a\" should be less than \"b\". got=%d, %t", cmp, ok)
+	}
+	if _, ok := Compare(&Array{}, &Array{}); ok {
+		t.Errorf("arrays should not be ordered")
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	one := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	same := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	other := &Array{Elements: []Object{&String{Value: "a"}, &Integer{Value: 1}}}
+
+	if one.HashKey() != same.HashKey() {
+		t.Errorf("arrays with same content have different hash keys")
+	}
+	if one.HashKey() == other.HashKey() {
+		t.Errorf("arrays with different content have same hash keys")
+	}
+
+	if _, ok := ToHashable(&Array{Elements: []Object{&Builtin{}}}); ok {
+		t.Errorf("array containing a builtin should not be hashable")
+	}
+	cyclic := &Array{}
+	cyclic.Elements = []Object{cyclic}
+	if _, ok := ToHashable(cyclic); ok {
+		t.Errorf("array containing itself should not be hashable")
+	}
+
+	// 키로 넣은 배열은 얼린 복사본으로 저장된다.
+	hash := &Hash{}
+	hash.Set(one, &Integer{Value: 1})
+	key := hash.OrderedPairs()[0].Key.(*Array)
+	if key == one || !key.Frozen || one.Frozen {
+		t.Errorf("hash should store a frozen copy of the array key")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		expected string
+	}{
+		{&String{Value: "a, b"}, "a, b"},
+		{&Array{Elements: []Object{&String{Value: "a, b"}}}, `["a, b"]`},
+		{&Array{Elements: []Object{&String{Value: "\"q\" \\ \n\t ${x} $ 한\x00"}}}, `["\"q\" \\ \n\t \${x} $ 한\u{0}"]`},
+		{&CompiledFunction{Name: "fib", Parameters: []string{"n"}}, "<fn fib(n)>"},
+		{&Closure{Fn: &CompiledFunction{Parameters: []string{"x", "y"}}}, "<fn(x, y)>"},
+		{&Builtin{Name: "len"}, "<builtin len>"},
+	}
+	for i, tt := range tests {
+		if got := tt.obj.Inspect(); got != tt.expected {
+			t.Errorf("tests[%d] - wrong Inspect. want=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestPretty(t *testing.T) {
+	short := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	hash := &Hash{}
+	hash.Set(&String{Value: "numbers"}, &Array{Elements: []Object{
+		&Integer{Value: 100}, &Integer{Value: 200}, &Integer{Value: 300},
+	}})
+	hash.Set(&String{Value: "short"}, short)
+	nested := &Array{Elements: []Object{hash, &Array{}}}
+
+	tests := []struct {
+		obj      Object
+		width    int
+		expected string
+	}{
+		{&String{Value: "a"}, 80, `"a"`},
+		{short, 80, `[1, "a"]`},
+		{nested, 80, `[{"numbers": [100, 200, 300], "short": [1, "a"]}, []]`},
+		{nested, 40, `[
+  {
+    "numbers": [100, 200, 300],
+    "short": [1, "a"]
+  },
+  []
+]`},
+		{nested, 20, `[
+  {
+    "numbers": [
+      100,
+      200,
+      300
+    ],
+    "short": [
+      1,
+      "a"
+    ]
+  },
+  []
+]`},
+	}
+	for i, tt := range tests {
+		if got := Pretty(tt.obj, tt.width); got != tt.expected {
+			t.Errorf("tests[%d] - wrong Pretty output. want=\n%s\ngot=\n%s", i, tt.expected, got)
+		}
+	}
+
+	cyclic := &Array{Elements: []Object{&String{Value: "a long string that does not fit"}}}
+	cyclic.Elements = append(cyclic.Elements, cyclic)
+	if got := Pretty(cyclic, 10); got != "[\n  \"a long string that does not fit\",\n  [...]\n]" {
+		t.Errorf("wrong Pretty output for cyclic array. got=\n%s", got)
+	}
+}
+
+func TestCyclicInspect(t *testing.T) {
+	array := &Array{Elements: []Object{&Integer{Value: 1}}}
+	array.Elements = append(array.Elements, array)
+	if got := array.Inspect(); got != "[1, [...]]" {
+		t.Errorf("array containing itself has wrong Inspect. got=%q", got)
+	}
+
+	key := &String{Value: "self"}
+	hash := &Hash{}
+	hash.Set(key, &Array{Elements: []Object{hash}})
+	if got := hash.Inspect(); got != `{"self": [{...}]}` {
+		t.Errorf("hash containing itself has wrong Inspect. got=%q", got)
+	}
+
+	// 순환이 아니라 같은 배열을 두 번 담은 경우는 둘 다 출력한다.
+	shared := &Array{Elements: []Object{&Integer{Value: 2}}}
+	twice := &Array{Elements: []Object{shared, shared}}
+	if got := twice.Inspect(); got != "[[2], [2]]" {
+		t.Errorf("array sharing an element has wrong Inspect. got=%q", got)
 	}
 }
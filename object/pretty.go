@@ -0,0 +1,116 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 값 출력
+// Inspect는 puts처럼 값을 그대로 보여주지만, REPL은 값을 소스 코드에 가까운 모양으로 보여준다.
+// 문자열은 따옴표로 감싸고, 한 줄에 들어가지 않는 배열과 해시는 원소마다 줄을 바꿔 들여 쓴다.
+
+// 들여쓰기 한 단계
+const prettyIndent = "  "
+
+// 값을 width 글자 안에 들어가도록 출력한다.
+// 한 줄에 들어가면 한 줄로 출력하고, 넘치는 배열과 해시만 원소마다 줄을 바꾼다.
+// 원소 하나가 width보다 길면 그 줄은 넘칠 수 있다.
+func Pretty(obj Object, width int) string {
+	p := &prettyPrinter{width: width, seen: map[Object]bool{}}
+	return p.print(obj, 0, 0)
+}
+
+type prettyPrinter struct {
+	width int
+	seen  map[Object]bool // 출력 중인 컨테이너, 자기 자신을 담은 배열과 해시에서 순환을 끊는다.
+}
+
+// indent는 값이 놓인 줄의 들여쓰기, column은 값이 시작하는 열
+// 해시의 값은 키 뒤에서 시작하므로 column이 indent보다 크다.
+func (p *prettyPrinter) print(obj Object, indent, column int) string {
+	flat := inspect(obj, p.seen)
+	if column+utf8.RuneCountInString(flat) <= p.width || p.seen[obj] {
+		return flat
+	}
+
+	switch obj := obj.(type) {
+	case *Array:
+		if len(obj.Elements) == 0 {
+			return flat
+		}
+		p.seen[obj] = true
+		defer delete(p.seen, obj)
+
+		lines := make([]string, len(obj.Elements))
+		inner := indent + len(prettyIndent)
+		for i, e := range obj.Elements {
+			lines[i] = p.print(e, inner, inner)
+		}
+		return p.block("[", lines, "]", indent)
+
+	case *Hash:
+		if obj.Len() == 0 {
+			return flat
+		}
+		p.seen[obj] = true
+		defer delete(p.seen, obj)
+
+		var lines []string
+		inner := indent + len(prettyIndent)
+		for _, pair := range obj.pairs {
+			key := inspect(pair.Key, p.seen) + ": "
+			value := p.print(pair.Value, inner, inner+utf8.RuneCountInString(key))
+			lines = append(lines, key+value)
+		}
+		return p.block("{", lines, "}", indent)
+
+	default:
+		return flat
+	}
+}
+
+// 원소들을 한 줄에 하나씩 한 단계 더 들여 써서 괄호로 감싼다.
+func (p *prettyPrinter) block(open string, lines []string, close string, indent int) string {
+	spaces := strings.Repeat(" ", indent)
+
+	var out strings.Builder
+	out.WriteString(open + "\n")
+	for i, line := range lines {
+		out.WriteString(spaces + prettyIndent + line)
+		if i < len(lines)-1 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString(spaces + close)
+	return out.String()
+}
+
+// 문자열을 다시 읽을 수 있는 문자열 리터럴로 만든다.
+// 렉서가 읽는 이스케이프 시퀀스만 사용한다: \n \t \" \\ \$ \u{...}
+func quoteString(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i, ch := range s {
+		switch {
+		case ch == '"' || ch == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(ch)
+		case ch == '\n':
+			out.WriteString(`\n`)
+		case ch == '\t':
+			out.WriteString(`\t`)
+		// ${는 문자열 보간이 시작되므로 \${로 쓴다.
+		case ch == '$' && strings.HasPrefix(s[i+1:], "{"):
+			out.WriteString(`\$`)
+		case !unicode.IsPrint(ch):
+			fmt.Fprintf(&out, `\u{%X}`, ch)
+		default:
+			out.WriteRune(ch)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
@@ -0,0 +1,33 @@
+package parser
+
+import (
+	"MonkeyKids/token"
+	"fmt"
+	"strings"
+)
+
+// 파싱 에러
+// 문자열 대신 구조화된 에러를 사용하면 에디터나 REPL 같은 도구가
+// 에러 위치와 기대한 토큰을 직접 꺼내 쓸 수 있다.
+type ParseError struct {
+	Pos      token.Position    // 에러가 발생한 소스 위치
+	Expected []token.TokenType // 이 위치에 올 수 있었던 토큰들 (없으면 nil)
+	Actual   token.Token       // 실제로 만난 토큰
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// 기대한 토큰 목록을 사람이 읽기 좋은 문자열로 만든다.
+func expectedString(expected []token.TokenType) string {
+	if len(expected) == 1 {
+		return string(expected[0])
+	}
+	var names []string
+	for _, t := range expected {
+		names = append(names, string(t))
+	}
+	return "one of " + strings.Join(names, ", ")
+}
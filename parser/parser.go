@@ -17,6 +17,7 @@ import (
 	"MonkeyKids/lexer"
 	"MonkeyKids/token"
 	"fmt"
+	"math/big"
 	"strconv"
 )
 
@@ -29,11 +30,20 @@ const (
 	// *연산자가 == 연산자보다 우선순위가 높은가?
 	// 전위 연산자가 호출 표현식보다우선순위가 높은가?
 	LOWEST
+	ASSIGN      // =
+	NULLISH     // ??
+	OR          // ||
+	AND         // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
+	POWER       // **
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
@@ -42,7 +52,14 @@ type Parser struct {
 	l              *lexer.Lexer // 현재의 렉서 인스턴스를 가리키는 포인터
 	curToken       token.Token  // 현재 토큰
 	peekToken      token.Token  // 그다음 토큰
-	errors         []string     // 문자열 슬라이스
+	errors         []*ParseError
+	panicking      bool                // 에러를 만나 다음 명령문 경계까지 토큰을 버리는 중인지 여부
+	comments       []*ast.CommentGroup // 지금까지 읽은 모든 주석
+	curDoc         *ast.CommentGroup   // curToken 바로 앞의 주석
+	curComment     *ast.CommentGroup   // curToken 뒤에서 curToken과 같은 줄에 나온 주석
+	peekDoc        *ast.CommentGroup   // peekToken 바로 앞의 주석
+	loopDepth      int                 // 현재 함수 안에서 감싸고 있는 반복문의 수, break와 continue 검사에 사용
+	brackets       []token.TokenType   // curToken까지 열리고 아직 닫히지 않은 (, {, [
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
@@ -59,7 +76,7 @@ type (
 // 그 후에 각각의 파싱 함수는 자기가 할 일을 수행하고 보통은 AST노드를 생성
 // 그리고 다시 parseProgram 내의 메인루프가 토큰을 진행하고 다음에 무엇을 해야할지 결정해야 한다.
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []*ParseError{}}
 	// 토큰을 2개 읽어서 curToken, peekToken 을 세팅
 	p.nextToken()
 	p.nextToken()
@@ -67,15 +84,19 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionExpression)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE, p.parseInterpolatedString)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 
@@ -88,23 +109,159 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.NULLISH, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.OPTIONAL_CHAIN, p.parseOptionalChain)
 	return p
 }
 
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+func (p *Parser) peekError(expected ...token.TokenType) {
+	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
+		expectedString(expected), p.peekToken.Type)
+	p.addError(p.peekToken, expected, msg)
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*ParseError {
 	return p.errors
 }
 
+// 패닉 모드 에러 복구
+// 명령문 하나에서 첫 번째 에러만 기록하고, 그 뒤로는 명령문 경계에 도달할 때까지 에러를 무시한다.
+// 그래야 오타 하나가 연쇄적으로 엉뚱한 에러를 만들어내지 않는다.
+func (p *Parser) addError(tok token.Token, expected []token.TokenType, msg string) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+	p.errors = append(p.errors, &ParseError{Pos: tok.Pos, Expected: expected, Actual: tok, Message: msg})
+}
+
+// 다음 명령문 경계까지 토큰을 버린다.
+// 경계는 ';', 블록을 닫는 '}', let, return 이며 curToken이 망가진 명령문의 마지막 토큰에 있는 상태로 끝낸다.
+// 닫히지 않은 소괄호나 대괄호 뒤에 나온 let과 return도 경계이다.
+// 호출한 쪽의 반복문이 nextToken을 호출하면 다음 명령문에서 파싱을 다시 시작하게 된다.
+//
+// depth는 명령문을 읽던 반복문의 괄호 깊이이다. 에러가 괄호 안에서 났으면 그 괄호가 닫힐 때까지 버려야
+// 닫는 괄호에서 파싱을 다시 시작해 엉뚱한 에러를 내지 않는다. 최상위에서 짝이 없는 닫는 괄호도 함께 버린다.
+// 망가진 명령문이 블록을 닫는 '}'까지 읽었으면 curToken이 이미 블록 밖에 있으므로 바로 돌아간다.
+func (p *Parser) synchronize(depth int) {
+	p.panicking = false
+
+	for p.depth() >= depth && !p.curTokenIs(token.EOF) {
+		// let과 return은 소괄호나 대괄호 바로 안에 올 수 없으므로 닫히지 않은 괄호 뒤의 새 명령문이다.
+		// 괄호 하나가 닫히지 않았다고 뒤의 에러가 모두 가려지지 않도록 그 괄호들을 닫힌 것으로 본다.
+		if p.peekTokenIs(token.LET) || p.peekTokenIs(token.RETURN) {
+			p.dropOpenParens(depth)
+		}
+		if p.depth() == depth {
+			if p.curTokenIs(token.SEMICOLON) {
+				return
+			}
+			switch p.peekToken.Type {
+			case token.LET, token.RETURN, token.WHILE, token.FOR:
+				return
+			case token.RBRACE:
+				if depth > 0 {
+					return
+				}
+			}
+		}
+		if p.peekTokenIs(token.EOF) {
+			return
+		}
+		p.nextToken()
+	}
+}
+
+// 닫는 괄호는 짝이 맞는 여는 괄호까지 닫는다. 그 사이에 닫히지 않은 괄호는 함께 닫힌 것으로 본다.
+// 짝이 없는 닫는 괄호는 무시한다.
+func (p *Parser) trackBrackets() {
+	var open token.TokenType
+	switch p.curToken.Type {
+	case token.LPAREN, token.LBRACE, token.LBRACKET:
+		p.brackets = append(p.brackets, p.curToken.Type)
+		return
+	case token.RPAREN:
+		open = token.LPAREN
+	case token.RBRACE:
+		open = token.LBRACE
+	case token.RBRACKET:
+		open = token.LBRACKET
+	default:
+		return
+	}
+	for i := len(p.brackets) - 1; i >= 0; i-- {
+		if p.brackets[i] == open {
+			p.brackets = p.brackets[:i]
+			return
+		}
+	}
+}
+
+// depth보다 깊은 곳에서 가장 안쪽부터 이어진 닫히지 않은 소괄호와 대괄호를 버린다.
+// 렉서도 그 괄호들을 잊어야 뒤의 개행이 다시 명령문을 끝낸다.
+func (p *Parser) dropOpenParens(depth int) {
+	n := len(p.brackets)
+	for n > depth && (p.brackets[n-1] == token.LPAREN || p.brackets[n-1] == token.LBRACKET) {
+		n--
+	}
+	if n < len(p.brackets) {
+		p.l.DropOpenParens(len(p.brackets) - n)
+		p.brackets = p.brackets[:n]
+	}
+}
+
+// 괄호 깊이
+func (p *Parser) depth() int {
+	return len(p.brackets)
+}
+
+// 렉서가 COMMENT 토큰을 내보내면 파싱 함수들이 보지 않도록 여기서 걸러낸다.
+// 연달아 나온 주석은 하나의 묶음이 되어 바로 뒤 토큰에 붙고, 명령문의 첫 토큰에 붙은 묶음은 그 명령문의 Doc이 된다.
+// 단, 앞 토큰과 같은 줄에서 시작한 주석은 앞 토큰에 붙고, 명령문의 마지막 토큰에 붙은 묶음은 그 명령문의 Comment가 된다.
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
+	p.curDoc = p.peekDoc
+	p.curComment = nil
+	p.peekDoc = nil
+
+	p.trackBrackets()
+
 	p.peekToken = p.l.NextToken()
+	for p.peekToken.Type == token.COMMENT {
+		comment := &ast.Comment{Token: p.peekToken}
+		if p.peekDoc == nil && comment.Pos().Line == p.curToken.Pos.Line {
+			if p.curComment == nil {
+				p.curComment = &ast.CommentGroup{}
+				p.comments = append(p.comments, p.curComment)
+			}
+			p.curComment.List = append(p.curComment.List, comment)
+		} else {
+			if p.peekDoc == nil {
+				p.peekDoc = &ast.CommentGroup{}
+				p.comments = append(p.comments, p.peekDoc)
+			}
+			p.peekDoc.List = append(p.peekDoc.List, comment)
+		}
+		p.peekToken = p.l.NextToken()
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -113,31 +270,67 @@ func (p *Parser) ParseProgram() *ast.Program {
 	// token.EOF를 만날때 까지 모든 토큰을 대상으로 for-loop문을 반복적으로 호출
 	for p.curToken.Type != token.EOF {
 		stmt := p.ParseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize(0)
+		} else if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	program.Comments = p.comments
 	return program
 }
 
 // 파서가 표현식을 파싱할 수 있게 만든다.
 // 명령문이 LET, RETURN 밖에 없기 때문에 ,이 두경우가 아닐경우 표현식문으로 파싱
+// 파싱에 실패하면 nil 인터페이스를 반환한다. (nil 포인터를 담은 인터페이스가 아니다)
 func (p *Parser) ParseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		if stmt := p.parseLetStatement(); stmt != nil {
+			stmt.Comment = p.curComment
+			p.checkLoopControl(stmt.Value)
+			return stmt
+		}
 	case token.RETURN:
-		return p.parseReturnStatement()
-
+		if stmt := p.parseReturnStatement(); stmt != nil {
+			stmt.Comment = p.curComment
+			p.checkLoopControl(stmt.ReturnValue)
+			return stmt
+		}
+	case token.WHILE:
+		if stmt := p.parseWhileStatement(); stmt != nil {
+			stmt.Comment = p.curComment
+			p.checkLoopControl(stmt.Condition)
+			return stmt
+		}
+	case token.FOR:
+		if stmt := p.parseForStatement(); stmt != nil {
+			stmt.Comment = p.curComment
+			p.checkLoopControl(stmt.Iterable)
+			return stmt
+		}
+	case token.BREAK, token.CONTINUE:
+		if stmt := p.parseLoopControlStatement(); stmt != nil {
+			return stmt
+		}
 	default:
-		return p.parseExpressionStatement()
-
+		if stmt := p.parseExpressionStatement(); stmt != nil {
+			stmt.Comment = p.curComment
+			if exp, ok := stmt.Expression.(*ast.IfExpression); ok {
+				// 명령문 자리의 if는 블록을 실행하기 전에 스택에 남긴 값이 없다.
+				p.checkLoopControl(exp.Condition)
+			} else {
+				p.checkLoopControl(stmt.Expression)
+			}
+			return stmt
+		}
 	}
+	return nil
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curToken}
+	stmt := &ast.LetStatement{Token: p.curToken, Doc: p.curDoc}
 
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -156,7 +349,7 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		fl.Name = stmt.Name.Value
 	}
 
-	for !p.peekTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
@@ -183,13 +376,21 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
-	stmt := &ast.ReturnStatement{Token: p.curToken}
+	stmt := &ast.ReturnStatement{Token: p.curToken, Doc: p.curDoc}
+
+	// 반환값 없는 return, 세미콜론이나 블록의 끝, 입력의 끝이 바로 따라온다.
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
 
 	p.nextToken()
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	for !p.peekTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
@@ -209,7 +410,7 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 // parsePrefixExpression은 PREFIX우선순위를 parseExpression에 넘기는데 전위 표현식을 파싱해야 한다.
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 
-	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt := &ast.ExpressionStatement{Token: p.curToken, Doc: p.curDoc}
 	stmt.Expression = p.parseExpression(LOWEST)
 
 	// 세미콜론은 선택적
@@ -222,7 +423,10 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 // 규격화된 에러메시지를 파서의 errors필드에 추가
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	if t == token.ILLEGAL {
+		msg = fmt.Sprintf("illegal token %q", p.curToken.Literal)
+	}
+	p.addError(p.curToken, nil, msg)
 }
 
 // parseExpression이 호출될 때, precedence의 값은 parseExpression메서드를 호출하는 현재의 시점에서 갖게 되는 오른쪽으로 묶이는
@@ -269,9 +473,26 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if err == nil {
+		lit.Value = value
+		return lit
+	}
+	// int64 범위를 넘는 리터럴은 큰 정수 리터럴이 된다.
+	if huge, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+		return &ast.BigIntegerLiteral{Token: p.curToken, Value: huge}
+	}
+	msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
+	p.addError(p.curToken, nil, msg)
+	return nil
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		p.addError(p.curToken, nil, msg)
 		return nil
 	}
 	lit.Value = value
@@ -300,12 +521,19 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 // 우선순위 값 자체는 이전에 정의한 상수,
 // 하나씩 값이 증가하는 정수 값이다.
 var precedences = map[token.TokenType]int{
+	token.ASSIGN: ASSIGN, token.PLUS_ASSIGN: ASSIGN, token.MINUS_ASSIGN: ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN, token.SLASH_ASSIGN: ASSIGN,
+	token.NULLISH: NULLISH, token.OR: OR, token.AND: AND,
 	token.EQ: EQUALS, token.NOT_EQ: EQUALS,
-	token.LT: LESSGREATER, token.GT: LESSGREATER,
+	token.LT: LESSGREATER, token.GT: LESSGREATER, token.LT_EQ: LESSGREATER, token.GT_EQ: LESSGREATER,
+	token.BIT_OR: BIT_OR, token.BIT_XOR: BIT_XOR, token.BIT_AND: BIT_AND,
+	token.SHIFT_LEFT: SHIFT, token.SHIFT_RIGHT: SHIFT,
 	token.PLUS: SUM, token.MINUS: SUM,
-	token.SLASH: PRODUCT, token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.SLASH: PRODUCT, token.ASTERISK: PRODUCT, token.PERCENT: PRODUCT,
+	token.POWER:          POWER,
+	token.LPAREN:         CALL,
+	token.LBRACKET:       INDEX,
+	token.OPTIONAL_CHAIN: INDEX,
 }
 
 // peekToken이 갖는 토큰 타입과 연관된 우선 순위를 반환
@@ -331,21 +559,74 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{Token: p.curToken, Operator: p.curToken.Literal, Left: left}
 
 	precedence := p.curPrecedence()
+	// 거듭제곱은 오른쪽으로 묶인다. 2 ** 3 ** 2는 2 ** (3 ** 2)
+	if expression.Operator == "**" {
+		precedence--
+	}
 	p.nextToken()
-	// "+" 연산자를 우결합하게 만들기 위해
-	// 예) (a + b) + c) 가 아니라 ((a + (b + c))
-	if expression.Operator == "+" {
-		expression.Right = p.parseExpression(precedence - 1) // 이 값을 감소시켜서 RBP를 작게 만들어야함
-	} else {
-		expression.Right = p.parseExpression(precedence)
+	expression.Right = p.parseExpression(precedence)
+
+	return expression
+}
+
+// 대입은 오른쪽으로 묶인다. a = b = 1은 a = (b = 1)
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	if !isAssignable(left) {
+		p.addError(p.curToken, nil, fmt.Sprintf("cannot assign to %s", left.String()))
+		return nil
 	}
+
+	expression := &ast.AssignExpression{Token: p.curToken, Operator: p.curToken.Literal, Target: left}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+	if expression.Value == nil {
+		return nil
+	}
+
 	return expression
 }
 
+// 식별자와 인덱스 표현식에만 대입할 수 있다.
+// h?.[a][b]는 h가 null이면 체인 전체를 건너뛰어 대입할 곳이 없으므로, 체인에 ?.가 있으면 대입 대상이 될 수 없다.
+func isAssignable(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.IndexExpression:
+		return !isOptionalChain(exp)
+	}
+	return false
+}
+
+// 인덱스와 호출 체인의 어느 고리에든 ?.가 있는지 확인한다.
+func isOptionalChain(exp ast.Expression) bool {
+	for {
+		switch e := exp.(type) {
+		case *ast.IndexExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Left
+		case *ast.CallExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Function
+		default:
+			return false
+		}
+	}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 
@@ -388,20 +669,179 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken, Doc: p.curDoc}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	stmt.Condition = p.parseExpression(LOWEST)
+	if stmt.Condition == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.loopDepth++
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth--
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// for (x in xs) { } 또는 for (k, v in xs) { }
+// 변수가 둘이면 첫째가 키(배열과 문자열은 인덱스), 둘째가 값이다.
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken, Doc: p.curDoc}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Key = stmt.Value
+		stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+
+	stmt.Iterable = p.parseExpression(LOWEST)
+	if stmt.Iterable == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.loopDepth++
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth--
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// break와 continue는 값을 만드는 표현식 안의 if 블록에서 쓸 수 없다.
+// 가상 머신이 반쯤 만든 피연산자를 스택에 남긴 채 반복문을 빠져나가고, 평가기는 BREAK를 값으로 쓰게 되기 때문이다.
+// 함수 리터럴은 새 반복문 문맥이라 건너뛴다. 블록 안의 다른 명령문은 그 명령문을 파싱할 때 따로 검사한다.
+// 이미 에러가 난 명령문은 검사하지 않는다.
+func (p *Parser) checkLoopControl(exp ast.Expression) {
+	if exp == nil || p.panicking {
+		return
+	}
+	ast.Inspect(exp, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral, *ast.MacroLiteral:
+			return false
+		case *ast.IfExpression:
+			p.checkLoopControl(node.Condition)
+			p.checkLoopControlInBlock(node.Consequence)
+			p.checkLoopControlInBlock(node.Alternative)
+			return false
+		}
+		return true
+	})
+}
+
+// 값을 만드는 if의 블록에서 break와 continue를 찾는다. 블록 안의 명령문 자리 if도 같은 값을 만드는 중이다.
+func (p *Parser) checkLoopControlInBlock(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+	for _, stmt := range block.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.BreakStatement:
+			p.addCheckError(stmt.Token, "break inside expression")
+		case *ast.ContinueStatement:
+			p.addCheckError(stmt.Token, "continue inside expression")
+		case *ast.ExpressionStatement:
+			if exp, ok := stmt.Expression.(*ast.IfExpression); ok {
+				p.checkLoopControlInBlock(exp.Consequence)
+				p.checkLoopControlInBlock(exp.Alternative)
+			}
+		}
+	}
+}
+
+// 파싱을 마친 명령문에서 찾은 에러는 토큰을 버릴 필요가 없으므로 panicking으로 바꾸지 않는다.
+func (p *Parser) addCheckError(tok token.Token, msg string) {
+	p.errors = append(p.errors, &ParseError{Pos: tok.Pos, Actual: tok, Message: msg})
+}
+
+// break와 continue는 반복문 안에서만 쓸 수 있다.
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	tok, doc := p.curToken, p.curDoc
+	if p.loopDepth == 0 {
+		p.addError(tok, nil, fmt.Sprintf("%s outside loop", tok.Literal))
+		return nil
+	}
+
+	// break와 continue 뒤에는 값이 올 수 없다. 명령문은 ;, 개행, 블록을 닫는 }, 입력 끝에서 끝나야 한다.
+	switch p.peekToken.Type {
+	case token.SEMICOLON:
+		p.nextToken()
+	case token.RBRACE, token.EOF:
+	default:
+		p.addError(p.peekToken, []token.TokenType{token.SEMICOLON, token.RBRACE}, "expected end of statement")
+		return nil
+	}
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok, Doc: doc, Comment: p.curComment}
+	}
+	return &ast.ContinueStatement{Token: tok, Doc: doc, Comment: p.curComment}
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 
 	block.Statements = []ast.Statement{}
+	depth := p.depth()
 
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.ParseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize(depth)
+			// 망가진 명령문이 블록을 닫는 }까지 읽었으면 블록이 끝난 것이다.
+			if p.depth() < depth {
+				return block
+			}
+		} else if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	if !p.curTokenIs(token.RBRACE) {
+		p.addError(p.curToken, []token.TokenType{token.RBRACE}, "expected } to close block, got EOF instead")
+	}
 	return block
 }
 
@@ -417,7 +857,32 @@ func (p *Parser) parseFunctionExpression() ast.Expression {
 		return nil
 	}
 
+	// 함수 본문은 바깥 반복문과 상관없다.
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
+	lit.End = p.curToken.Pos
+
+	return lit
+}
+
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	lit.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	lit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
 
 	return lit
 }
@@ -431,21 +896,27 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 
-	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.peekTokenIs(token.RPAREN) {
+		p.peekError(token.COMMA, token.RPAREN)
 		return nil
 	}
+	p.nextToken()
 
 	return identifiers
 
@@ -483,6 +954,39 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// TEMPLATE (표현식 TEMPLATE)* 표현식 TEMPLATE_END
+// 빈 문자열 조각은 버린다.
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	p.appendStringPart(str)
+
+	for {
+		p.nextToken()
+		part := p.parseExpression(LOWEST)
+		if part == nil {
+			return nil
+		}
+		str.Parts = append(str.Parts, part)
+
+		if p.peekTokenIs(token.TEMPLATE) {
+			p.nextToken()
+			p.appendStringPart(str)
+			continue
+		}
+		if !p.expectPeek(token.TEMPLATE_END) {
+			return nil
+		}
+		p.appendStringPart(str)
+		return str
+	}
+}
+
+func (p *Parser) appendStringPart(str *ast.InterpolatedString) {
+	if p.curToken.Literal != "" {
+		str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+	}
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
@@ -507,9 +1011,11 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 		p.nextToken()
 		list = append(list, p.parseExpression(LOWEST))
 	}
-	if !p.expectPeek(end) {
+	if !p.peekTokenIs(end) {
+		p.peekError(token.COMMA, end)
 		return nil
 	}
+	p.nextToken()
 	return list
 }
 
@@ -525,10 +1031,33 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// h?.[k] 또는 f?.(x)
+// ?. 뒤에는 인덱스 연산자나 호출 표현식이 와야 한다.
+func (p *Parser) parseOptionalChain(left ast.Expression) ast.Expression {
+	switch {
+	case p.peekTokenIs(token.LBRACKET):
+		p.nextToken()
+		exp, ok := p.parseIndexExpression(left).(*ast.IndexExpression)
+		if !ok {
+			return nil
+		}
+		exp.Optional = true
+		return exp
+	case p.peekTokenIs(token.LPAREN):
+		p.nextToken()
+		exp := p.parseCallExpression(left).(*ast.CallExpression)
+		exp.Optional = true
+		return exp
+	default:
+		p.peekError(token.LBRACKET, token.LPAREN)
+		return nil
+	}
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
 
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Pairs = []ast.HashPair{}
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
@@ -541,11 +1070,15 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 
-		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+		if !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.COMMA) {
+			p.peekError(token.COMMA, token.RBRACE)
 			return nil
 		}
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		}
 	}
 	if !p.expectPeek(token.RBRACE) {
 		return nil
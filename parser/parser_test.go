@@ -3,7 +3,9 @@ package parser
 import (
 	"MonkeyKids/ast"
 	"MonkeyKids/lexer"
+	"MonkeyKids/token"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -165,6 +167,56 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	input := "18446744073709551614;"
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.BigIntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.BigIntegerLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value.String() != "18446744073709551614" {
+		t.Errorf("literal.Value not %s. got=%s", "18446744073709551614", literal.Value)
+	}
+	if literal.String() != "18446744073709551614" {
+		t.Errorf("literal.String() not %s. got=%s", "18446744073709551614", literal.String())
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "2.5e-1;"
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != 0.25 {
+		t.Errorf("literal.Value not %g. got=%g", 0.25, literal.Value)
+	}
+	if literal.TokenLiteral() != "2.5e-1" {
+		t.Errorf("literal.TokenLiteral() not %s. got=%s", "2.5e-1", literal.TokenLiteral())
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -437,18 +489,48 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"a + b / c", "(a + (b / c))"},
 		{"a + b * c + d / e - f", "(((a + (b * c)) + (d / e)) - f)"},
 		{"3 + 4; -5 * 5", "(3 + 4)((-5) * 5)"},
+		{"x = 5", "(x = 5)"},
+		{"x += y * 2 == z", "(x += ((y * 2) == z))"},
+		{"a = b -= c", "(a = (b -= c))"},
+		{"f(x *= 2, y /= 3)", "f((x *= 2), (y /= 3))"},
+		{"a[0] = b[1] + 1", "((a[0]) = ((b[1]) + 1))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"a == b && c < d", "((a == b) && (c < d))"},
+		{"!a || b", "((!a) || b)"},
+		{"x = a || b", "(x = (a || b))"},
+		{"h[\"k\"][i] += 2", "(((h[k])[i]) += 2)"},
+		{"a <= b == c >= d", "((a <= b) == (c >= d))"},
+		{"a * b % c", "((a * b) % c)"},
+		{"a + b % c", "(a + (b % c))"},
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"-2 ** 2", "(-(2 ** 2))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a & b == c", "((a & b) == c)"},
+		{"1 << 2 + 3", "(1 << (2 + 3))"},
+		{"a << b & c >> d", "((a << b) & (c >> d))"},
+		{"a | b < c", "((a | b) < c)"},
+		{"x ^ y && z", "((x ^ y) && z)"},
+		{"a ?? b || c", "(a ?? (b || c))"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"x = a ?? null", "(x = (a ?? null))"},
+		{"h?.[k]", "(h?.[k])"},
+		{"f?.(x)?.[0]", "(f?.(x)?.[0])"},
+		{"a?.[0] + b?.(1)", "((a?.[0]) + b?.(1))"},
+		{"-a?.[0]", "(-(a?.[0]))"},
 
 		{"5 > 4 == 3 < 4", "((5 > 4) == (3 < 4))"},
 		{"5 < 4 != 3 > 4", "((5 < 4) != (3 > 4))"},
-		{"3 + 4 * 5 == 3 * 1 + 4 * 5", "((3 + (4 * 5)) == ((3 * 1) + (4 * 5))"},
+		{"3 + 4 * 5 == 3 * 1 + 4 * 5", "((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))"},
 
 		{"true", "true"},
 		{"false", "false"},
 		{"3 > 5 == false", "((3 > 5) == false)"},
 		{"3 < 5 == true", "((3 < 5) == true)"},
 
-		{"a * [1, 2, 3, 4][b * c] *d", "((a * ([(1, 2, 3, 4)][(b * c)])) * d)"},
-		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2]), (b[1]), (2 * ([1, 2][1])))"},
+		{"a * [1, 2, 3, 4][b * c] *d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
+		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
 	}
 	for _, tt := range tests {
 
@@ -481,15 +563,22 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong legnth. got=%d", len(hash.Pairs))
 	}
 
-	expected := map[string]int64{"one": 1, "two": 2, "three": 3}
+	// 쌍은 소스에 나온 순서대로 담겨 있어야 한다.
+	expected := []struct {
+		key   string
+		value int64
+	}{{"one", 1}, {"two", 2}, {"three", 3}}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for i, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
+			continue
 		}
-		expectedValue := expected[literal.String()]
-		testIntegerLiteral(t, value, expectedValue)
+		if literal.String() != expected[i].key {
+			t.Errorf("pair %d has wrong key. want=%q, got=%q", i, expected[i].key, literal.String())
+		}
+		testIntegerLiteral(t, pair.Value, expected[i].value)
 	}
 }
 
@@ -524,7 +613,7 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	if !ok {
 		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
 	}
-	if len(hash.Pairs) != 0 {
+	if len(hash.Pairs) != 3 {
 		t.Errorf("hash.Pairs has wrong legnth. got=%d", len(hash.Pairs))
 	}
 	tests := map[string]func(ast.Expression){
@@ -538,10 +627,10 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			testInfixExpression(t, e, 15, "/", 5)
 		},
 	}
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 
@@ -550,12 +639,12 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			t.Errorf("No test function for key %q found", literal.String())
 			continue
 		}
-		testFunc(value)
+		testFunc(pair.Value)
 	}
 }
 
 func TestIfExpression(t *testing.T) {
-	input := `if (x < y) { x } else { y }`
+	input := `if (x < y) { x }`
 	l := lexer.New(input)
 	p := New(l)
 	program := p.ParseProgram()
@@ -624,14 +713,56 @@ func TestFunctionExpression(t *testing.T) {
 			function.Body.Statements[0])
 	}
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+
+	// 'fn' 토큰부터 몸체를 닫는 }까지가 함수를 정의한 소스 범위
+	if function.Pos().String() != "1:1" || function.End.String() != "1:19" {
+		t.Errorf("function has wrong source span. got=%s-%s", function.Pos(), function.End)
+	}
 }
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d\n", len(macro.Parameters))
+	}
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n", len(macro.Body.Statements))
+	}
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T", macro.Body.Statements[0])
+	}
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
 func TestFunctionParameterExpression(t *testing.T) {
 	tests := []struct {
 		input          string
 		expectedParams []string
 	}{
 		{"fn() {}", []string{}},
-		{"fn() {x}", []string{"x"}},
+		{"fn(x) {}", []string{"x"}},
 		{"fn(x, y, z) {}", []string{"x", "y", "z"}},
 	}
 	for _, tt := range tests {
@@ -678,3 +809,541 @@ func TestCallExpressionExpression(t *testing.T) {
 	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let = 5;", "1:5: expected next token to be IDENT, got = instead"},
+		{"let x = 5;\nlet y 10;", "2:7: expected next token to be =, got INT instead"},
+		{"1 + ;", "1:5: no prefix parse function for ; found"},
+		{"let x = 1;\nx + 1 = 2", "2:7: cannot assign to (x + 1)"},
+		{"f() = 2", "1:5: cannot assign to f()"},
+		{"h?.[0] = 1", "1:8: cannot assign to (h?.[0])"},
+		{"h?.[0][1] = 1", "1:11: cannot assign to ((h?.[0])[1])"},
+		{"f?.()[1] += 1", "1:10: cannot assign to (f?.()[1])"},
+		{"h?.x", "1:4: expected next token to be one of [, (, got IDENT instead"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestNodePosition(t *testing.T) {
+	input := "let add = fn(a, b) {\n  a + b\n};"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	fn := let.Value.(*ast.FunctionLiteral)
+	body := fn.Body.Statements[0].(*ast.ExpressionStatement)
+	infix := body.Expression.(*ast.InfixExpression)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{program, "1:1"},
+		{let, "1:1"},
+		{let.Name, "1:5"},
+		{fn, "1:11"},
+		{fn.Body, "1:20"},
+		{infix, "2:5"},
+		{infix.Left, "2:3"},
+		{infix.Right, "2:7"},
+	}
+	for _, tt := range tests {
+		if tt.node.Pos().String() != tt.expected {
+			t.Errorf("wrong position for %q. want=%s, got=%s", tt.node.String(), tt.expected, tt.node.Pos())
+		}
+	}
+}
+
+// 오타 하나는 에러 하나만 만들고, 나머지 명령문은 정상적으로 파싱되어야 한다.
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements []string
+	}{
+		{
+			"let a = 1;\nlet b = ;\nlet c = 3;\nlet d = c + 1;",
+			[]string{"2:9: no prefix parse function for ; found"},
+			[]string{"let a = 1;", "let c = 3;", "let d = (c + 1);"},
+		},
+		{
+			"let = 1; let x 2; let y = 3;",
+			[]string{
+				"1:5: expected next token to be IDENT, got = instead",
+				"1:16: expected next token to be =, got INT instead",
+			},
+			[]string{"let y = 3;"},
+		},
+		{
+			"let a = 1 + * 2 + + 3; return a;",
+			[]string{"1:13: no prefix parse function for * found"},
+			[]string{"return a;"},
+		},
+		{
+			"let f = fn() { let = 1; 2 }; let g = 5;",
+			[]string{"1:20: expected next token to be IDENT, got = instead"},
+			[]string{"let f = fn<f>()2;", "let g = 5;"},
+		},
+		{
+			"add(1, 2 3); let x = 1;",
+			[]string{"1:10: expected next token to be one of ,, ), got INT instead"},
+			[]string{"let x = 1;"},
+		},
+		{
+			"fn(x) { x",
+			[]string{"1:10: expected } to close block, got EOF instead"},
+			[]string{},
+		},
+		// 괄호 안에서 난 에러는 그 괄호가 닫힐 때까지 버린다.
+		{
+			"fn(a b) { a }; let x = 1;",
+			[]string{"1:6: expected next token to be one of ,, ), got IDENT instead"},
+			[]string{"let x = 1;"},
+		},
+		{
+			"{1: 2 3: 4}; let x = 1;",
+			[]string{"1:7: expected next token to be one of ,, }, got INT instead"},
+			[]string{"let x = 1;"},
+		},
+		{
+			`let h = {"a": 1 "b": 2, "c": 3}; let x = 1;`,
+			[]string{"1:17: expected next token to be one of ,, }, got STRING instead"},
+			[]string{"let x = 1;"},
+		},
+		{
+			"if (x > ) { let a = 1; a } else { 2 }; let x = 1;",
+			[]string{"1:9: no prefix parse function for ) found"},
+			[]string{"let x = 1;"},
+		},
+		// 닫히지 않은 소괄호나 대괄호 뒤의 let과 return에서 다시 시작하므로 뒤의 에러도 알린다.
+		{
+			"let a = (1 + * 2\nlet b = 1\nlet c = * 3",
+			[]string{"1:14: no prefix parse function for * found", "3:9: no prefix parse function for * found"},
+			[]string{"let b = 1;"},
+		},
+		// 렉서도 괄호를 잊으므로 개행이 다시 명령문을 끝낸다.
+		{
+			"let a = [1, * 2\nlet b = 1\n-1",
+			[]string{"1:13: no prefix parse function for * found"},
+			[]string{"let b = 1;", "(-1)"},
+		},
+		{
+			"let f = fn() { g(1 + * 2\nreturn 3 }; let x = 1;",
+			[]string{"1:22: no prefix parse function for * found"},
+			[]string{"let f = fn<f>()return 3;;", "let x = 1;"},
+		},
+		{
+			"let a = f((1, * \nreturn 2",
+			[]string{"1:13: expected next token to be ), got , instead"},
+			[]string{"return 2;"},
+		},
+		// 블록 안에서 닫히지 않은 괄호는 블록을 닫는 }가 함께 닫는다.
+		{
+			"let f = fn() { let a = [1, 2; a + 1 }; let x = 1;",
+			[]string{"1:29: expected next token to be one of ,, ], got ; instead"},
+			[]string{"let f = fn<f>();", "let x = 1;"},
+		},
+		{
+			"let f = fn() { 1 + }; let x = 1;",
+			[]string{"1:20: no prefix parse function for } found"},
+			[]string{"let f = fn<f>();", "let x = 1;"},
+		},
+		// 최상위의 짝이 없는 닫는 괄호
+		{
+			"1; ) ] } let x = 1;",
+			[]string{"1:4: no prefix parse function for ) found"},
+			[]string{"1", "let x = 1;"},
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. want=%d, got=%d (%v)",
+				tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+		for i, msg := range tt.expectedErrors {
+			if errors[i].Error() != msg {
+				t.Errorf("wrong error. want=%q, got=%q", msg, errors[i])
+			}
+		}
+
+		if len(program.Statements) != len(tt.expectedStatements) {
+			t.Errorf("wrong number of statements for %q. want=%d, got=%d",
+				tt.input, len(tt.expectedStatements), len(program.Statements))
+			continue
+		}
+		for i, expected := range tt.expectedStatements {
+			if program.Statements[i].String() != expected {
+				t.Errorf("wrong statement. want=%q, got=%q", expected, program.Statements[i].String())
+			}
+		}
+	}
+}
+
+func TestParseErrorFields(t *testing.T) {
+	l := lexer.New("let x 5;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. got=%d", len(errors))
+	}
+	err := errors[0]
+	if err.Pos.Line != 1 || err.Pos.Column != 7 {
+		t.Errorf("wrong position. got=%s", err.Pos)
+	}
+	if len(err.Expected) != 1 || err.Expected[0] != token.ASSIGN {
+		t.Errorf("wrong expected tokens. got=%v", err.Expected)
+	}
+	if err.Actual.Type != token.INT || err.Actual.Literal != "5" {
+		t.Errorf("wrong actual token. got=%+v", err.Actual)
+	}
+	if err.Message != "expected next token to be =, got INT instead" {
+		t.Errorf("wrong message. got=%q", err.Message)
+	}
+}
+
+func TestOptionalSemicolons(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedStatements []string
+	}{
+		{"let x = 5", []string{"let x = 5;"}},
+		{"return x", []string{"return x;"}},
+		{"return", []string{"return ;"}},
+		{"let x = 5\nlet y = x\nx + y", []string{"let x = 5;", "let y = x;", "(x + y)"}},
+		{"let f = fn(x) {\n\tif (x) { return }\n\treturn x\n}", []string{"let f = fn<f>(x)ifx return ;return x;;"}},
+		{"let x = 1 +\n2\n* 3", []string{"let x = (1 + (2 * 3));"}},
+		{"add(1,\n2)\n-3", []string{"add(1, 2)", "(-3)"}},
+		{"if (x) {\n1\n}\nelse {\n2\n}", []string{"ifx 1else2"}},
+		{"let h = {\n\"a\":\n1\n}", []string{"let h = {a:1};"}},
+		{"let a = [\n1,\n2\n]", []string{"let a = [1, 2];"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != len(tt.expectedStatements) {
+			t.Fatalf("%q: wrong number of statements. want=%d, got=%d (%q)",
+				tt.input, len(tt.expectedStatements), len(program.Statements), program.String())
+		}
+		for i, stmt := range program.Statements {
+			if stmt.String() != tt.expectedStatements[i] {
+				t.Errorf("%q: statements[%d] wrong. want=%q, got=%q",
+					tt.input, i, tt.expectedStatements[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// 더하기 함수
+// 두 인자를 더한다.
+let add = fn(a, b) {
+	/* 결과 */
+	return a + b // 끝
+}
+add(1, 2)`
+
+	l := lexer.New(input)
+	l.KeepComments(true)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+	if program.String() != "let add = fn<add>(a, b)return (a + b);;add(1, 2)" {
+		t.Errorf("comments changed the program. got=%q", program.String())
+	}
+	if len(program.Comments) != 3 {
+		t.Fatalf("program.Comments wrong. want=3, got=%d", len(program.Comments))
+	}
+
+	let := program.Statements[0].(*ast.LetStatement)
+	if let.Doc == nil || let.Doc.Text() != "더하기 함수\n두 인자를 더한다." {
+		t.Fatalf("let.Doc wrong. got=%+v", let.Doc)
+	}
+	if let.Doc.Pos().Line != 1 {
+		t.Errorf("let.Doc.Pos wrong. got=%s", let.Doc.Pos())
+	}
+
+	body := let.Value.(*ast.FunctionLiteral).Body
+	ret := body.Statements[0].(*ast.ReturnStatement)
+	if ret.Doc == nil || ret.Doc.Text() != "결과" {
+		t.Errorf("ret.Doc wrong. got=%+v", ret.Doc)
+	}
+	if ret.Comment == nil || ret.Comment.Text() != "끝" {
+		t.Errorf("ret.Comment wrong. got=%+v", ret.Comment)
+	}
+
+	call := program.Statements[1].(*ast.ExpressionStatement)
+	if call.Doc != nil {
+		t.Errorf("trailing comment attached to next statement. got=%q", call.Doc.String())
+	}
+	if program.Comments[2].String() != "// 끝" {
+		t.Errorf("program.Comments[2] wrong. got=%q", program.Comments[2].String())
+	}
+}
+
+func TestTrailingComments(t *testing.T) {
+	input := `let x = 1; // 하나
+// 둘
+let y = 1 + /* 안 */ 2; /* 셋 */ // 넷
+while (y) { break // 다섯
+}`
+
+	l := lexer.New(input)
+	l.KeepComments(true)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+
+	x := program.Statements[0].(*ast.LetStatement)
+	if x.Comment == nil || x.Comment.Text() != "하나" {
+		t.Errorf("x.Comment wrong. got=%+v", x.Comment)
+	}
+	// 다음 줄의 주석은 앞 명령문이 아니라 뒤 명령문의 Doc이다.
+	y := program.Statements[1].(*ast.LetStatement)
+	if y.Doc == nil || y.Doc.Text() != "둘" {
+		t.Errorf("y.Doc wrong. got=%+v", y.Doc)
+	}
+	if y.Comment == nil || y.Comment.Text() != "셋\n넷" {
+		t.Errorf("y.Comment wrong. got=%+v", y.Comment)
+	}
+	body := program.Statements[2].(*ast.WhileStatement).Body
+	brk := body.Statements[0].(*ast.BreakStatement)
+	if brk.Comment == nil || brk.Comment.Text() != "다섯" {
+		t.Errorf("brk.Comment wrong. got=%+v", brk.Comment)
+	}
+
+	// 표현식 안의 주석은 어느 명령문에도 붙지 않아서 Program.Comments에만 있고 Inspect도 방문하지 않는다.
+	var groups []string
+	for _, group := range program.Comments {
+		groups = append(groups, group.Text())
+	}
+	if strings.Join(groups, "|") != "하나|둘|안|셋\n넷|다섯" {
+		t.Errorf("program.Comments wrong. got=%q", groups)
+	}
+	var visited []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if group, ok := node.(*ast.CommentGroup); ok {
+			visited = append(visited, group.Text())
+		}
+		return true
+	})
+	if strings.Join(visited, "|") != "하나|둘|셋\n넷|다섯" {
+		t.Errorf("visited comments wrong. got=%q", visited)
+	}
+}
+
+func TestInterpolatedString(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedParts []string
+	}{
+		{`"Hello ${name}, you are ${age + 1}"`, []string{"Hello ", "name", ", you are ", "(age + 1)"}},
+		{`"${x}"`, []string{"x"}},
+		{`"${a}${b}!"`, []string{"a", "b", "!"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		str, ok := stmt.Expression.(*ast.InterpolatedString)
+		if !ok {
+			t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+		}
+		if len(str.Parts) != len(tt.expectedParts) {
+			t.Fatalf("wrong number of parts. want=%d, got=%d", len(tt.expectedParts), len(str.Parts))
+		}
+		for i, part := range str.Parts {
+			if part.String() != tt.expectedParts[i] {
+				t.Errorf("parts[%d] wrong. want=%q, got=%q", i, tt.expectedParts[i], part.String())
+			}
+		}
+	}
+
+	l := lexer.New(`"a ${1 +} b"; "\q"`)
+	p := New(l)
+	p.ParseProgram()
+	if len(p.Errors()) != 2 || p.Errors()[1].Message != `illegal token "\\q"` {
+		t.Errorf("unexpected errors: %v", p.Errors())
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < 10) { if (x) { break; } continue; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.WhileStatement. got=%T", program.Statements[0])
+	}
+	if !testInfixExpression(t, stmt.Condition, "x", "<", 10) {
+		return
+	}
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d", len(stmt.Body.Statements))
+	}
+	if _, ok := stmt.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("body.Statements[1] is not *ast.ContinueStatement. got=%T", stmt.Body.Statements[1])
+	}
+	if stmt.String() != "while(x < 10) ifx break;continue;" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		key      string
+		value    string
+		expected string
+	}{
+		{`for (x in xs) { x }`, "", "x", "for(x in xs) x"},
+		{`for (k, v in {"a": 1}) { break; }`, "k", "v", "for(k, v in {a:1}) break;"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ForStatement. got=%T", program.Statements[0])
+		}
+		if tt.key == "" {
+			if stmt.Key != nil {
+				t.Errorf("stmt.Key is not nil. got=%q", stmt.Key)
+			}
+		} else if !testIdentifier(t, stmt.Key, tt.key) {
+			return
+		}
+		if !testIdentifier(t, stmt.Value, tt.value) {
+			return
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+// 값을 만드는 표현식 안의 break와 continue는 에러이다. 명령문 자리의 if 안에서는 쓸 수 있다.
+func TestLoopControlInsideExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (i < 1000) { i = i + 1; let a = [i, if (true) { continue } else { 1 }] }", "1:55: continue inside expression"},
+		{"while (true) { 1 + if (true) { break } }", "1:32: break inside expression"},
+		{"for (x in xs) { [x, if (true) { continue } else { 1 }] }", "1:33: continue inside expression"},
+		{"while (true) { let a = if (x) { if (y) { break } } }", "1:42: break inside expression"},
+		{"while (true) { f(if (x) { break }) }", "1:27: break inside expression"},
+		{"while (true) { if (if (x) { break }) { 1 } }", "1:29: break inside expression"},
+		{"while (true) { return if (x) { 1 } else { continue } }", "1:43: continue inside expression"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) != 1 {
+			t.Errorf("%q: expected 1 error. got=%v", tt.input, p.Errors())
+			continue
+		}
+		if p.Errors()[0].Error() != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, p.Errors()[0].Error())
+		}
+	}
+
+	valid := []string{
+		"while (true) { if (x) { break } }",
+		"while (true) { if (x) { if (y) { continue } } else { break } }",
+		"while (true) { let a = [if (x) { while (y) { break } }] }",
+		"while (true) { let f = fn() { while (y) { [if (x) { 1 }] } } }",
+	}
+	for _, input := range valid {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Errorf("%q: unexpected errors %v", input, p.Errors())
+		}
+	}
+}
+
+func TestLoopControlErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break outside loop"},
+		{"if (true) { continue }", "1:13: continue outside loop"},
+		{"while (true) { fn() { break } }", "1:23: break outside loop"},
+		{"for (x in xs) { fn() { continue } }", "1:24: continue outside loop"},
+		{"while (true) { break 5 }", "1:22: expected end of statement"},
+		{"for (x in xs) { continue x }", "1:26: expected end of statement"},
+		{"while (true) { break (1) }", "1:22: expected end of statement"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) != 1 {
+			t.Fatalf("%q: expected 1 error. got=%v", tt.input, p.Errors())
+		}
+		if p.Errors()[0].Error() != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, p.Errors()[0].Error())
+		}
+	}
+}
@@ -7,17 +7,26 @@ package repl
 
 import (
 	"MonkeyKids/compiler"
+	"MonkeyKids/evaluator"
 	"MonkeyKids/lexer"
 	"MonkeyKids/object"
 	"MonkeyKids/parser"
+	"MonkeyKids/token"
 	"MonkeyKids/vm"
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 )
 
 const PROMPT = ">>"
 
+// 결과를 출력할 때 한 줄의 최대 글자 수, 넘치는 배열과 해시는 여러 줄로 나눠 출력한다.
+const LINE_WIDTH = 80
+
+// 입력이 아직 끝나지 않았을 때(닫히지 않은 블록 등) 다음 행을 기다리는 프롬프트
+const CONTINUE_PROMPT = ".."
+
 // 컴파일러와 가상머신을 REPL 에 연동
 // 면저 입력을 토큰화하고 파싱한 다음, 컴파일하고 프로그래밍을 실행하면 된다.
 // 그리고 전에는 Eval 함수에서 반환값을 출력했지만, 이번에는 가상 머신 스택
@@ -28,30 +37,64 @@ func Start(in io.Reader, out io.Writer) {
 	var constants []object.Object
 	globals := make([]object.Object, vm.GlobalsSize)
 	symbolTable := compiler.NewSymbolTable()
+	// 매크로는 입력이 바뀌어도 계속 쓸 수 있도록 환경을 유지한다.
+	macroEnv := object.NewEnvironment()
 
 	for i, v := range object.Builtins {
 		symbolTable.DefineBuiltin(i, v.Name)
 	}
 
+	input := ""
 	for {
-		fmt.Fprintf(out, PROMPT)
+		if input == "" {
+			fmt.Fprintf(out, PROMPT)
+		} else {
+			fmt.Fprintf(out, CONTINUE_PROMPT)
+		}
 		scanned := scanner.Scan()
 		if !scanned {
+			// 입력이 끝났는데 명령문이 완성되지 않았으면 에러를 보여주고 끝낸다.
+			if input != "" {
+				p := parser.New(lexer.New(input))
+				p.ParseProgram()
+				printParserErrors(out, p.Errors())
+			}
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
+		if input != "" {
+			input += "\n"
+		}
+		input += scanner.Text()
+		l := lexer.New(input)
 		p := parser.New(l)
 
 		program := p.ParseProgram()
 		if len(p.Errors()) != 0 {
+			// 입력의 끝에서 에러가 났다면 다음 행을 더 읽어본다.
+			if incomplete(p.Errors()) {
+				continue
+			}
 			printParserErrors(out, p.Errors())
+			input = ""
+			continue
+		}
+		input = ""
+
+		// 컴파일하기 전에 매크로를 정의하고 확장한다.
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, err := evaluator.ExpandMacros(program, macroEnv)
+		if err != nil {
+			fmt.Fprintf(out, "Woops! Macro expansion failed:\n %s\n", err)
+			continue
+		}
+		// 빈 행이나 주석만 있는 행, 매크로 정의만 있는 행
+		if len(program.Statements) == 0 {
 			continue
 		}
 
 		comp := compiler.NewWithStates(symbolTable, constants)
-		err := comp.Compile(program)
+		err = comp.Compile(expanded)
 		if err != nil {
 			fmt.Fprintf(out, "Woops! Compilation failed:\n %s\n", err)
 			continue
@@ -67,14 +110,27 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 		lastPopped := machine.LastPoppedStackElem()
-		io.WriteString(out, lastPopped.Inspect())
+		io.WriteString(out, object.Pretty(lastPopped, LINE_WIDTH))
 		io.WriteString(out, "\n")
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+// 파싱 에러가 모두 입력의 끝(EOF)이나 닫히지 않은 블록 주석에서 생겼다면 아직 입력 중인 명령문이다.
+func incomplete(errors []*parser.ParseError) bool {
+	for _, err := range errors {
+		if err.Actual.Type == token.ILLEGAL && strings.HasPrefix(err.Actual.Literal, "/*") {
+			continue
+		}
+		if err.Actual.Type != token.EOF {
+			return false
+		}
+	}
+	return true
+}
+
+func printParserErrors(out io.Writer, errors []*parser.ParseError) {
+	for _, err := range errors {
+		io.WriteString(out, "\t"+err.Error()+"\n")
 	}
 }
 
@@ -91,11 +147,11 @@ const MONKEY_FACE = `            __,__
            '-----'
 `
 
-func printParseErrors(out io.Writer, errors []string) {
+func printParseErrors(out io.Writer, errors []*parser.ParseError) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Woops! we ran into some monkey business here!\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, err := range errors {
+		io.WriteString(out, "\t"+err.Error()+"\n")
 	}
 }
@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 // 서로 다른 여러 값을 TokenType으로 필요한 만큼 사용가능
 // 여러 토큰을 서로 쉽게 구별 가능
 // int나 byte의 성능 이점을 따라 가기는 힘듬
@@ -8,17 +10,51 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // 토큰이 시작하는 소스 위치
+}
+
+// 소스 위치
+// 렉싱에서 생긴 에러와 파싱, 컴파일, 실행 중에 생긴 에러가 소스의 어느 곳에서 발생했는지 알려주기 위해 사용
+type Position struct {
+	Filename string // 파일 이름 (없으면 빈 문자열)
+	Line     int    // 행 번호, 1부터 시작
+	Column   int    // 열 번호, 1부터 시작
+}
+
+// 렉서가 만든 위치인지 확인한다. 직접 만든 토큰은 위치 정보가 없다.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+// file:line:column 또는 line:column 형태로 표시
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+	if p.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 const (
 	ILLEGAL = "ILLEGAL" // 토큰이나 문자를 렉서가 알 수 없다는 것
 	EOF     = "EOF"     // 파일의 끝
+	COMMENT = "COMMENT" // 주석, 렉서가 주석을 남기도록 설정했을 때만 나온다.
 
 	// 식별자 + 리터럴
-	IDENT  = " IDENT"
+	IDENT  = "IDENT"
 	INT    = "INT"
+	FLOAT  = "FLOAT"  // 3.14, 1e-3
 	STRING = "STRING" // 문자열 지원
 
+	// 문자열 보간 "Hello ${name}!"
+	// TEMPLATE은 ${ 앞까지의 문자열 조각, TEMPLATE_END는 마지막 } 뒤부터 닫는 " 까지의 조각
+	// 조각 사이에는 보간할 표현식의 토큰들이 온다.
+	TEMPLATE     = "TEMPLATE"
+	TEMPLATE_END = "TEMPLATE_END"
+
 	// 연산자
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -28,6 +64,30 @@ const (
 	SLASH    = "/"
 	LT       = "<"
 	GT       = ">"
+	LT_EQ    = "<="
+	GT_EQ    = ">="
+	PERCENT  = "%"
+	POWER    = "**"
+	AND      = "&&"
+	OR       = "||"
+
+	// null 처리 연산자
+	// a ?? b는 a가 null일 때만 b를 평가한다. h?.[k], f?.(x)는 h나 f가 null이면 인덱스나 인수를 평가하지 않고 null이 된다.
+	NULLISH        = "??"
+	OPTIONAL_CHAIN = "?."
+
+	// 비트 연산자
+	BIT_AND     = "&"
+	BIT_OR      = "|"
+	BIT_XOR     = "^"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
+
+	// 복합 대입 연산자
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
 
 	// 구분자
 	COMMA     = ","
@@ -45,22 +105,36 @@ const (
 
 	// 예약어
 	FUNCTION = "FUNCTION"
+	MACRO    = "MACRO"
 	LET      = "LET"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
+	NULL     = "NULL"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"macro":    MACRO,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"null":     NULL,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
 }
 
 // 주어진 식별자가 예약어인지 확인
@@ -3,6 +3,7 @@ package vm
 import (
 	"MonkeyKids/code"
 	"MonkeyKids/object"
+	"MonkeyKids/token"
 )
 
 // 함수른 중첩해서 호출할 수 있으며, 호출과 관계된 정보는 후입선출방식으로 접근한다.
@@ -30,3 +31,8 @@ func NewFrame(cl *object.Closure, basePointer int) *Frame {
 func (f *Frame) Instructions() code.Instructions {
 	return f.cl.Fn.Instructions
 }
+
+// 현재 실행 중인 명령어의 소스 위치
+func (f *Frame) Position() token.Position {
+	return f.cl.Fn.SourceMap.Lookup(f.ip)
+}
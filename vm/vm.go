@@ -11,7 +11,10 @@ import (
 	"MonkeyKids/code"
 	"MonkeyKids/compiler"
 	"MonkeyKids/object"
+	"MonkeyKids/token"
 	"fmt"
+	"math"
+	"math/big"
 )
 
 const GlobalsSize = 65536
@@ -35,7 +38,8 @@ type VM struct {
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
-	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions,
+		SourceMap: bytecode.SourceMap}
 	mainClosure := &object.Closure{Fn: mainFn}
 	mainFrame := NewFrame(mainClosure, 0)
 
@@ -52,8 +56,30 @@ func New(bytecode *compiler.Bytecode) *VM {
 	}
 }
 
-// 인출-복호화-실행 주기가 구현
+// 실행 중 발생한 에러와 에러를 일으킨 명령어의 소스 위치
+type RuntimeError struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *RuntimeError) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// 에러가 발생하면 그 시점에 실행하던 명령어의 소스 위치를 붙여서 반환한다.
 func (vm *VM) Run() error {
+	err := vm.run()
+	if err != nil {
+		return &RuntimeError{Pos: vm.currentFrame().Position(), Message: err.Error()}
+	}
+	return nil
+}
+
+// 인출-복호화-실행 주기가 구현
+func (vm *VM) run() error {
 	var ip int
 	var ins code.Instructions
 	var op code.Opcode
@@ -77,7 +103,8 @@ func (vm *VM) Run() error {
 				return err
 			}
 
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight:
 			err := vm.executeBinaryOperation(op)
 			if err != nil {
 				return err
@@ -98,7 +125,8 @@ func (vm *VM) Run() error {
 				return err
 			}
 
-		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan:
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan,
+			code.OpLessThanOrEqual, code.OpGreaterThanOrEqual:
 			err := vm.executeComparison(op)
 			if err != nil {
 				return err
@@ -129,6 +157,14 @@ func (vm *VM) Run() error {
 				vm.currentFrame().ip = pos - 1
 			}
 
+		case code.OpJumpNull:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			if vm.Pop() == Null {
+				vm.currentFrame().ip = pos - 1
+			}
+
 			// 조건식은 표현식이면 표현식이라면 어떤 것과도 바꿔 쓸 수 있다. : 어떤 표현식이든 가상 머신에서 Null을 만들 수 있다.
 			// 가상머신에서는 executeBinaryOperation처럼 의도하지 않은 값이 발생하면 에러처리
 			// 명시적으로 Null을 처리해야 하는 함수와 메서드가 있다. : executeBangOperator
@@ -187,6 +223,28 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case code.OpSetIndex:
+			value := vm.Pop()
+			index := vm.Pop()
+			left := vm.Pop()
+
+			err := vm.executeSetIndex(left, index, value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpDup:
+			n := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+
+			start := vm.sp - n
+			for i := 0; i < n; i++ {
+				err := vm.Push(vm.stack[start+i])
+				if err != nil {
+					return err
+				}
+			}
+
 		case code.OpCall:
 			numArgs := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1 // 피연산자 자리에 빈 바이트 하나를 추가한다.
@@ -197,6 +255,9 @@ func (vm *VM) Run() error {
 
 		case code.OpReturnValue:
 			returnValue := vm.Pop()
+			if vm.framesIndex == 1 {
+				return vm.exit(returnValue)
+			}
 
 			frame := vm.popFrame()
 			vm.sp = frame.basePointer - 1
@@ -207,6 +268,9 @@ func (vm *VM) Run() error {
 			}
 
 		case code.OpReturn:
+			if vm.framesIndex == 1 {
+				return vm.exit(Null)
+			}
 			frame := vm.popFrame()
 			vm.sp = frame.basePointer - 1 // 1을 빼는 이유는 최적화때문에
 
@@ -220,15 +284,25 @@ func (vm *VM) Run() error {
 			vm.currentFrame().ip += 1
 
 			frame := vm.currentFrame()
-			vm.stack[frame.basePointer+int(localIndex)] = vm.Pop()
+			slot := &vm.stack[frame.basePointer+int(localIndex)]
+			// 클로저에 붙잡힌 지역 변수는 셀에 저장해야 클로저도 바뀐 값을 본다.
+			if cell, ok := (*slot).(*object.Cell); ok {
+				cell.Value = vm.Pop()
+			} else {
+				*slot = vm.Pop()
+			}
 
 		case code.OpGetLocal:
 			localIndex := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1
 
 			frame := vm.currentFrame()
+			value := vm.stack[frame.basePointer+int(localIndex)]
+			if cell, ok := value.(*object.Cell); ok {
+				value = cell.Value
+			}
 
-			err := vm.Push(vm.stack[frame.basePointer+int(localIndex)])
+			err := vm.Push(value)
 			if err != nil {
 				return err
 			}
@@ -258,6 +332,40 @@ func (vm *VM) Run() error {
 			freeIndex := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1
 
+			currentClosure := vm.currentFrame().cl
+			err := vm.Push(currentClosure.Free[freeIndex].Value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			currentClosure.Free[freeIndex].Value = vm.Pop()
+
+		case code.OpCaptureLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			slot := &vm.stack[frame.basePointer+int(localIndex)]
+			cell, ok := (*slot).(*object.Cell)
+			if !ok {
+				cell = &object.Cell{Value: *slot}
+				*slot = cell
+			}
+
+			err := vm.Push(cell)
+			if err != nil {
+				return err
+			}
+
+		case code.OpCaptureFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
 			currentClosure := vm.currentFrame().cl
 			err := vm.Push(currentClosure.Free[freeIndex])
 			if err != nil {
@@ -270,11 +378,73 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case code.OpToString:
+			err := vm.Push(object.ToString(vm.Pop()))
+			if err != nil {
+				return err
+			}
+
+		case code.OpIter:
+			iterable := vm.Pop()
+			// range(0, 10, 0)처럼 내장 함수가 돌려준 에러는 평가기처럼 그 메시지를 그대로 알린다.
+			if err, ok := iterable.(*object.Error); ok {
+				return fmt.Errorf("%s", err.Message)
+			}
+			iterator, ok := object.Iterate(iterable)
+			if !ok {
+				return fmt.Errorf("cannot iterate over %s", iterable.Type())
+			}
+			err := vm.Push(iterator)
+			if err != nil {
+				return err
+			}
+
+		case code.OpIterNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			count := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.executeIterNext(pos, int(count))
+			if err != nil {
+				return err
+			}
+
 		}
 	}
 	return nil
 }
 
+// 반복자는 스택 가장 위에 둔 채로 다음 요소를 그 위에 넣는다.
+// 다 돌았으면 반복자를 버리고 반복문 뒤로 점프한다.
+func (vm *VM) executeIterNext(pos int, count int) error {
+	iterator, ok := vm.stack[vm.sp-1].(*object.Iterator)
+	if !ok {
+		return fmt.Errorf("iterator expected on stack, got %T", vm.stack[vm.sp-1])
+	}
+
+	if count == 2 {
+		key, value, ok := iterator.NextPair()
+		if !ok {
+			vm.Pop()
+			vm.currentFrame().ip = pos - 1
+			return nil
+		}
+		err := vm.Push(key)
+		if err != nil {
+			return err
+		}
+		return vm.Push(value)
+	}
+
+	value, ok := iterator.Next()
+	if !ok {
+		vm.Pop()
+		vm.currentFrame().ip = pos - 1
+		return nil
+	}
+	return vm.Push(value)
+}
+
 func (vm *VM) Push(o object.Object) error {
 	if vm.sp >= StackSize {
 		return fmt.Errorf("stack overflow")
@@ -292,6 +462,13 @@ func (vm *VM) Pop() object.Object {
 	return o
 }
 
+// 최상위의 return은 메인 프레임을 꺼내지 않고 프로그램을 끝낸다.
+// 평가기처럼 돌려준 값이 프로그램의 결과가 되도록 마지막으로 꺼낸 값 자리에 둔다.
+func (vm *VM) exit(value object.Object) error {
+	vm.stack[vm.sp] = value
+	return nil
+}
+
 func (vm *VM) LastPoppedStackElem() object.Object {
 	return vm.stack[vm.sp]
 }
@@ -307,6 +484,14 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
 		return vm.executeBinaryIntegerOperation(op, left, right)
 
+	// 한쪽이 큰 정수면 math/big으로 계산
+	case isInteger(left) && isInteger(right):
+		return vm.executeBinaryBigIntegerOperation(op, left, right)
+
+	// 한쪽이라도 실수면 실수로 계산
+	case isNumber(left) && isNumber(right):
+		return vm.executeBinaryFloatOperation(op, left, right)
+
 	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
 		return vm.executeBinaryStringOperation(op, left, right)
 	default:
@@ -319,6 +504,111 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left object.Object,
 	leftValue := left.(*object.Integer).Value
 	rightValue := right.(*object.Integer).Value
 	var result int64
+	ok := true
+
+	switch op {
+	case code.OpAdd:
+		result, ok = object.AddInt64(leftValue, rightValue)
+	case code.OpSub:
+		result, ok = object.SubInt64(leftValue, rightValue)
+	case code.OpMul:
+		result, ok = object.MulInt64(leftValue, rightValue)
+	case code.OpDiv:
+		if rightValue == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		if leftValue == math.MinInt64 && rightValue == -1 {
+			ok = false
+		} else {
+			result = leftValue / rightValue
+		}
+	case code.OpMod:
+		if rightValue == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result = leftValue % rightValue
+	case code.OpBitAnd:
+		result = leftValue & rightValue
+	case code.OpBitOr:
+		result = leftValue | rightValue
+	case code.OpBitXor:
+		result = leftValue ^ rightValue
+	case code.OpShiftLeft:
+		if rightValue >= 0 && rightValue < 63 && (leftValue<<rightValue)>>rightValue == leftValue {
+			result = leftValue << rightValue
+		} else {
+			ok = false
+		}
+	case code.OpShiftRight:
+		if rightValue >= 0 {
+			result = leftValue >> rightValue
+		} else {
+			ok = false
+		}
+	// 거듭제곱은 쉽게 넘치므로 언제나 큰 정수로 계산하고 결과를 다시 줄인다.
+	case code.OpPow:
+		ok = false
+	default:
+		return fmt.Errorf("unknown integer operator: %d", op)
+	}
+	// int64 범위를 넘으면 큰 정수로 다시 계산
+	if !ok {
+		return vm.executeBinaryBigIntegerOperation(op, left, right)
+	}
+	return vm.Push(&object.Integer{Value: result})
+}
+
+// 결과가 int64에 들어가면 다시 Integer가 된다.
+func (vm *VM) executeBinaryBigIntegerOperation(op code.Opcode, left object.Object, right object.Object) error {
+	leftValue, _ := object.ToBigInt(left)
+	rightValue, _ := object.ToBigInt(right)
+	result := new(big.Int)
+
+	switch op {
+	case code.OpAdd:
+		result.Add(leftValue, rightValue)
+	case code.OpSub:
+		result.Sub(leftValue, rightValue)
+	case code.OpMul:
+		result.Mul(leftValue, rightValue)
+	case code.OpDiv:
+		if rightValue.Sign() == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result.Quo(leftValue, rightValue)
+	case code.OpMod:
+		if rightValue.Sign() == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result.Rem(leftValue, rightValue)
+	case code.OpBitAnd:
+		result.And(leftValue, rightValue)
+	case code.OpBitOr:
+		result.Or(leftValue, rightValue)
+	case code.OpBitXor:
+		result.Xor(leftValue, rightValue)
+	case code.OpPow:
+		obj, err := object.PowInteger(leftValue, rightValue)
+		if err != nil {
+			return err
+		}
+		return vm.Push(obj)
+	case code.OpShiftLeft, code.OpShiftRight:
+		obj, err := object.ShiftInteger(leftValue, rightValue, op == code.OpShiftLeft)
+		if err != nil {
+			return err
+		}
+		return vm.Push(obj)
+	default:
+		return fmt.Errorf("unknown integer operator: %d", op)
+	}
+	return vm.Push(object.NewInteger(result))
+}
+
+func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left object.Object, right object.Object) error {
+	leftValue, _ := object.ToFloat(left)
+	rightValue, _ := object.ToFloat(right)
+	var result float64
 
 	switch op {
 	case code.OpAdd:
@@ -329,10 +619,17 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left object.Object,
 		result = leftValue * rightValue
 	case code.OpDiv:
 		result = leftValue / rightValue
+	case code.OpMod:
+		result = math.Mod(leftValue, rightValue)
+	case code.OpPow:
+		result = math.Pow(leftValue, rightValue)
+	// 비트 연산은 정수만 할 수 있다.
+	case code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight:
+		return fmt.Errorf("unsupported types for binary operation: %s %s", left.Type(), right.Type())
 	default:
-		return fmt.Errorf("unknown integer operator: %d", op)
+		return fmt.Errorf("unknown float operator: %d", op)
 	}
-	return vm.Push(&object.Integer{Value: result})
+	return vm.Push(&object.Float{Value: result})
 }
 
 func (vm *VM) executeComparison(op code.Opcode) error {
@@ -342,16 +639,40 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 	if left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ {
 		return vm.executeIntegerComparison(op, left, right)
 	}
+	if isInteger(left) && isInteger(right) {
+		return vm.executeBigIntegerComparison(op, left, right)
+	}
+	if isNumber(left) && isNumber(right) {
+		return vm.executeFloatComparison(op, left, right)
+	}
+	// 문자열, 배열, 해시는 값으로 비교한다.
 	switch op {
 	case code.OpEqual:
-		return vm.Push(nativeBoolToBooleanObject(right == left))
+		return vm.Push(nativeBoolToBooleanObject(object.Equal(left, right)))
 	case code.OpNotEqual:
-		return vm.Push(nativeBoolToBooleanObject(right != left))
-	default:
-		return fmt.Errorf("unknown operator: %d (%s %s)",
-			op, left.Type(), right.Type())
+		return vm.Push(nativeBoolToBooleanObject(!object.Equal(left, right)))
+	}
+	if cmp, ok := object.Compare(left, right); ok {
+		return vm.executeOrderComparison(op, cmp)
 	}
+	return fmt.Errorf("unknown operator: %d (%s %s)",
+		op, left.Type(), right.Type())
+}
 
+// object.Compare의 결과로 대소 비교를 처리한다.
+func (vm *VM) executeOrderComparison(op code.Opcode, cmp int) error {
+	switch op {
+	case code.OpGreaterThan:
+		return vm.Push(nativeBoolToBooleanObject(cmp > 0))
+	case code.OpLessThan:
+		return vm.Push(nativeBoolToBooleanObject(cmp < 0))
+	case code.OpLessThanOrEqual:
+		return vm.Push(nativeBoolToBooleanObject(cmp <= 0))
+	case code.OpGreaterThanOrEqual:
+		return vm.Push(nativeBoolToBooleanObject(cmp >= 0))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
 }
 
 func (vm *VM) executeIntegerComparison(op code.Opcode, left object.Object, right object.Object) error {
@@ -365,6 +686,57 @@ func (vm *VM) executeIntegerComparison(op code.Opcode, left object.Object, right
 		return vm.Push(nativeBoolToBooleanObject(rightValue != leftValue))
 	case code.OpGreaterThan:
 		return vm.Push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpLessThan:
+		return vm.Push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.Push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.Push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
+}
+
+func (vm *VM) executeBigIntegerComparison(op code.Opcode, left object.Object, right object.Object) error {
+	leftValue, _ := object.ToBigInt(left)
+	rightValue, _ := object.ToBigInt(right)
+	cmp := leftValue.Cmp(rightValue)
+
+	switch op {
+	case code.OpEqual:
+		return vm.Push(nativeBoolToBooleanObject(cmp == 0))
+	case code.OpNotEqual:
+		return vm.Push(nativeBoolToBooleanObject(cmp != 0))
+	case code.OpGreaterThan:
+		return vm.Push(nativeBoolToBooleanObject(cmp > 0))
+	case code.OpLessThan:
+		return vm.Push(nativeBoolToBooleanObject(cmp < 0))
+	case code.OpLessThanOrEqual:
+		return vm.Push(nativeBoolToBooleanObject(cmp <= 0))
+	case code.OpGreaterThanOrEqual:
+		return vm.Push(nativeBoolToBooleanObject(cmp >= 0))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
+}
+
+func (vm *VM) executeFloatComparison(op code.Opcode, left object.Object, right object.Object) error {
+	leftValue, _ := object.ToFloat(left)
+	rightValue, _ := object.ToFloat(right)
+
+	switch op {
+	case code.OpEqual:
+		return vm.Push(nativeBoolToBooleanObject(rightValue == leftValue))
+	case code.OpNotEqual:
+		return vm.Push(nativeBoolToBooleanObject(rightValue != leftValue))
+	case code.OpGreaterThan:
+		return vm.Push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpLessThan:
+		return vm.Push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.Push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.Push(nativeBoolToBooleanObject(leftValue >= rightValue))
 	default:
 		return fmt.Errorf("unknown operator: %d", op)
 	}
@@ -388,12 +760,27 @@ func (vm *VM) executeBangOperator() error {
 func (vm *VM) executeMinusOperator() error {
 	operand := vm.Pop()
 
-	if operand.Type() != object.INTEGER_OBJ {
+	switch operand := operand.(type) {
+	case *object.Integer:
+		if operand.Value == math.MinInt64 {
+			return vm.Push(object.NewInteger(new(big.Int).Neg(big.NewInt(operand.Value))))
+		}
+		return vm.Push(&object.Integer{Value: -operand.Value})
+	case *object.BigInteger:
+		return vm.Push(object.NewInteger(new(big.Int).Neg(operand.Value)))
+	case *object.Float:
+		return vm.Push(&object.Float{Value: -operand.Value})
+	default:
 		return fmt.Errorf("unsupported type for negation: %s", operand.Type())
 	}
+}
 
-	value := operand.(*object.Integer).Value
-	return vm.Push(&object.Integer{Value: -value})
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIG_INTEGER_OBJ
+}
+
+func isNumber(obj object.Object) bool {
+	return isInteger(obj) || obj.Type() == object.FLOAT_OBJ
 }
 
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
@@ -445,21 +832,19 @@ func (vm *VM) buildArray(startIndex int, endIndex int) object.Object {
 }
 
 func (vm *VM) buildHash(startIndex int, endIndex int) (object.Object, error) {
-	hashedParis := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{}
 
 	for i := startIndex; i < endIndex; i += 2 {
 		key := vm.stack[i]
 		value := vm.stack[i+1]
 
-		pair := object.HashPair{Key: key, Value: value}
-
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.ToHashable(key)
 		if !ok {
 			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
 		}
-		hashedParis[hashKey.HashKey()] = pair
+		hash.Set(hashKey, value)
 	}
-	return &object.Hash{Pairs: hashedParis}, nil
+	return hash, nil
 }
 
 func (vm *VM) executeIndexExpression(left object.Object, index object.Object) error {
@@ -467,6 +852,9 @@ func (vm *VM) executeIndexExpression(left object.Object, index object.Object) er
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return vm.executeArrayIndex(left, index)
 
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeStringIndex(left, index)
+
 	case left.Type() == object.HASH_OBJ:
 		return vm.executeHashIndex(left, index)
 
@@ -486,19 +874,58 @@ func (vm *VM) executeArrayIndex(array object.Object, index object.Object) error
 	return vm.Push(arrayObject.Elements[i])
 }
 
+// 문자열의 인덱스는 바이트가 아니라 문자 단위
+func (vm *VM) executeStringIndex(str object.Object, index object.Object) error {
+	ch := str.(*object.String).CharAt(index.(*object.Integer).Value)
+	if ch == nil {
+		return vm.Push(Null)
+	}
+	return vm.Push(ch)
+}
+
 func (vm *VM) executeHashIndex(hash object.Object, index object.Object) error {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.ToHashable(index)
 	if !ok {
 		return fmt.Errorf("unusable as hash key: %s", index.Type())
 	}
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Get(key)
 	if !ok {
 		return vm.Push(Null)
 	}
 	return vm.Push(pair.Value)
 }
+
+// 배열과 해시는 그 자리에서 바뀌므로 같은 객체를 가리키는 모든 바인딩이 바뀐 값을 본다.
+func (vm *VM) executeSetIndex(left, index, value object.Object) error {
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("array index must be INTEGER, got %s", index.Type())
+		}
+		if i.Value < 0 || i.Value >= int64(len(left.Elements)) {
+			return fmt.Errorf("index out of range: %d (length %d)", i.Value, len(left.Elements))
+		}
+		if left.Frozen {
+			return fmt.Errorf("cannot modify array used as hash key")
+		}
+		left.Elements[i.Value] = value
+
+	case *object.Hash:
+		key, ok := object.ToHashable(index)
+		if !ok {
+			return fmt.Errorf("unusable as hash key: %s", index.Type())
+		}
+		left.Set(key, value)
+
+	default:
+		return fmt.Errorf("index assignment not supported: %s", left.Type())
+	}
+	return vm.Push(value)
+}
+
 func (vm *VM) currentFrame() *Frame {
 	return vm.frames[vm.framesIndex-1]
 }
@@ -543,12 +970,22 @@ func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
 }
 func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
 	if numArgs != cl.Fn.NumParameters {
-		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+		return fmt.Errorf("wrong number of arguments to %s: want=%d, got=%d",
+			cl.Fn.Describe(), cl.Fn.NumParameters, numArgs)
 	}
-	frame := NewFrame(cl, vm.sp-numArgs)
+	// 지역 변수 슬롯을 비우기 전에 프레임과 스택이 넘치지 않는지 먼저 확인한다.
+	basePointer := vm.sp - numArgs
+	if vm.framesIndex >= MaxFrames || basePointer+cl.Fn.NumLocals >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+	frame := NewFrame(cl, basePointer)
 	vm.pushFrame(frame)
 
 	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	// 이전 호출이 남긴 셀에 값을 쓰지 않도록 인수가 아닌 지역 변수 슬롯을 비운다.
+	for i := frame.basePointer + numArgs; i < vm.sp; i++ {
+		vm.stack[i] = nil
+	}
 	return nil
 }
 
@@ -559,9 +996,15 @@ func (vm *VM) pushClosure(constIndex int, numFree int) error {
 	if !ok {
 		return fmt.Errorf("not a function: %+v", constant)
 	}
-	free := make([]object.Object, numFree)
+	// OpCurrentClosure로 올린 자기 자신은 셀이 아니므로 새 셀에 담는다.
+	free := make([]*object.Cell, numFree)
 	for i := 0; i < numFree; i++ {
-		free[i] = vm.stack[vm.sp-numFree+i]
+		value := vm.stack[vm.sp-numFree+i]
+		cell, ok := value.(*object.Cell)
+		if !ok {
+			cell = &object.Cell{Value: value}
+		}
+		free[i] = cell
 	}
 	vm.sp = vm.sp - numFree
 
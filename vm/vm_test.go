@@ -2,11 +2,15 @@ package vm
 
 import (
 	"MonkeyKids/ast"
+	"MonkeyKids/code"
 	"MonkeyKids/compiler"
+	"MonkeyKids/evaluator"
 	"MonkeyKids/lexer"
 	"MonkeyKids/object"
 	"MonkeyKids/parser"
 	"fmt"
+	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -97,6 +101,20 @@ func testExpectedObject(t *testing.T, expected interface{}, actual object.Object
 		if err != nil {
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
+	case *big.Int:
+		result, ok := actual.(*object.BigInteger)
+		if !ok {
+			t.Errorf("object is not BigInteger. got=%T (%+v)", actual, actual)
+			return
+		}
+		if result.Value.Cmp(expected) != 0 {
+			t.Errorf("object has wrong value. got=%s, want=%s", result.Value, expected)
+		}
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
 	case bool:
 		err := testBooleanObject(bool(expected), actual)
 		if err != nil {
@@ -136,15 +154,16 @@ func testExpectedObject(t *testing.T, expected interface{}, actual object.Object
 		if !ok {
 			t.Errorf("object not Hash: %T (%+v)", actual, actual)
 		}
-		if len(hash.Pairs) != len(expected) {
+		if hash.Len() != len(expected) {
 			t.Errorf("Hash has wrong number of Pairs. want=%d, got=%d",
-				len(expected), len(hash.Pairs))
+				len(expected), hash.Len())
 			return
 		}
-		for expectedKey, expectedValue := range expected {
-			pair, ok := hash.Pairs[expectedKey]
+		for _, pair := range hash.OrderedPairs() {
+			expectedValue, ok := expected[pair.Key.(object.Hashable).HashKey()]
 			if !ok {
-				t.Errorf("no pair for given key in Pairs")
+				t.Errorf("unexpected key in Pairs: %s", pair.Key.Inspect())
+				continue
 			}
 			err := testIntegerObject(expectedValue, pair.Value)
 			if err != nil {
@@ -178,6 +197,254 @@ func TestIntegerArithmetic(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"3.14", 3.14},
+		{"1e-3", 0.001},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 / 4", 2},
+		{"10 / 4.0", 2.5},
+		{"2 * 1.5 - 1", 2.0},
+		{"1 / 0.0 > 1e308", true},
+		{"1 < 1.5", true},
+		{"1.5 > 2", false},
+		{"1 == 1.0", true},
+		{"1 != 1.0", false},
+		{"0.1 + 0.2 == 0.3", false},
+		{"int(2.9)", 2},
+		{"int(-2.9)", -2},
+		{`int("42")`, 42},
+		{"float(3)", 3.0},
+		{`float("1e2")`, 100.0},
+		{`int("x")`, &object.Error{Message: `could not parse "x" as integer`}},
+		{"float(true)", &object.Error{Message: "argument to 'float' not supported, got BOOLEAN"}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7 % -3", 1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"2 ** 64", bigInt("18446744073709551616")},
+		{"2 ** -1", 0.5},
+		{"0 ** 0", 1},
+		{"12 & 10", 8},
+		{"12 | 10", 14},
+		{"12 ^ 10", 6},
+		{"-1 & 255", 255},
+		{"1 << 10", 1024},
+		{"1 << 63", bigInt("9223372036854775808")},
+		{"-1 << 63", -9223372036854775807 - 1},
+		{"1024 >> 3", 128},
+		{"-7 >> 1", -4},
+		{"-1 >> 100", -1},
+		{"(1 << 100) >> 98", 4},
+		{"(2 ** 64 + 5) % 2 ** 32", 5},
+		{"(2 ** 64) | 1 == 2 ** 64 + 1", true},
+		{"7.5 % 2", 1.5},
+		{"2.0 ** 0.5 * 2.0 ** 0.5 > 1.99", true},
+		{"4 ** 0.5", 2.0},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 1", true},
+		{"1 >= 2", false},
+		{"1 <= 1.5", true},
+		{"2.5 >= 3", false},
+		{"2 ** 64 >= 2 ** 63", true},
+		{"2 ** 64 <= 2 ** 63", false},
+	}
+	runVmTests(t, tests)
+}
+
+// 이항 연산자는 피연산자를 왼쪽부터 평가한다.
+func TestNullOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"null", Null},
+		{"null == null", true},
+		{"null != 1", true},
+		{"!null", true},
+		{"null ?? 1", 1},
+		{"false ?? 1", false},
+		{"0 ?? 1", 0},
+		{"null ?? null ?? 3", 3},
+		{`let h = {"a": 1}; h["b"] ?? 2`, 2},
+		{`let h = {"a": 1}; h?.["a"]`, 1},
+		{`let h = null; h?.["a"]`, Null},
+		{`let h = {"a": {"b": 2}}; h?.["a"]?.["b"]`, 2},
+		{`let h = {"a": 1}; h["x"]?.["b"] ?? "none"`, "none"},
+		{"let f = fn(x) { x * 2 }; f?.(21)", 42},
+		{"let f = null; f?.(21)", Null},
+		{"let f = null; f?.(21) ?? 0", 0},
+		// 왼쪽이 null이면 오른쪽은 평가하지 않는다.
+		{"let n = 0; let g = fn() { n += 1; n }; 1 ?? g(); null?.[g()]; null?.(g()); n", 0},
+		{"let n = 0; let g = fn() { n += 1; n }; null ?? g(); [1]?.[g() - 1]; n", 2},
+		// ?.가 null을 만나면 체인 전체를 건너뛴다.
+		{`let h = {}; h["a"]?.["b"]["c"]`, Null},
+		{`let h = {"a": null}; h["a"]?.["b"]["c"](1)`, Null},
+		{"let f = null; f?.(1)(2)[0]", Null},
+		{`let h = {"a": {"b": {"c": 3}}}; h["a"]?.["b"]["c"]`, 3},
+		{"let n = 0; let g = fn() { n += 1; n }; null?.[g()][g()](g()); n", 0},
+		// 인덱스와 인수 안의 체인은 바깥 체인을 건너뛰지 않는다.
+		{`[1, 2][null?.[0]["a"] ?? 1]`, 2},
+		{"let f = fn(x) { x ?? 7 }; f(null?.[0][1])", 7},
+	}
+	runVmTests(t, tests)
+}
+
+// 매크로는 컴파일하기 전에 확장한다. 확장한 프로그램은 평가기에서와 같은 결과를 내야 한다.
+func TestMacros(t *testing.T) {
+	tests := []vmTestCase{
+		{`let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };
+		unless(10 > 5, 1, 2)`, 2},
+		{`let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };
+		let n = 0; unless(n > 0, n += 10, n += 100); n`, 10},
+		{`let swap = macro(a, b) { quote(unquote(b) - unquote(a)) }; swap(2, 10) + swap(1, 2)`, 9},
+		{`let constant = macro() { quote(unquote(6 * 7)) }; let f = fn() { constant() }; f()`, 42},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+		env := object.NewEnvironment()
+		evaluator.DefineMacros(program, env)
+		expanded, err := evaluator.ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("macro expansion error: %s", err)
+		}
+
+		comp := compiler.New()
+		err = comp.Compile(expanded)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		testExpectedObject(t, tt.expected, vm.LastPoppedStackElem())
+	}
+}
+
+// 평가기와 가상 머신은 같은 프로그램에서 같은 값을 내야 한다.
+func TestEnginesAgree(t *testing.T) {
+	tests := []string{
+		"[if (true) { }]",
+		"[if (false) { 1 }]",
+		"[if (true) { let a = 1 }]",
+		"let f = fn() { let b = 1 }; [f()]",
+		"let f = fn() { }; [f(), f()]",
+		"let f = fn() { while (false) { } }; [f()]",
+		"let f = fn(x) { if (x) { let y = x } }; [f(true), f(false)]",
+		`let h = {"b": 1, [1, 2]: 2}; h[1.5] = 3; h["b"] = 4; [keys(h), values(h)]`,
+	}
+
+	for _, input := range tests {
+		evaluated := evaluator.Eval(parse(input), object.NewEnvironment())
+		if evaluated == nil {
+			t.Errorf("evaluator returned nil for %q", input)
+			continue
+		}
+
+		comp := compiler.New()
+		err := comp.Compile(parse(input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		if got, want := vm.LastPoppedStackElem().Inspect(), evaluated.Inspect(); got != want {
+			t.Errorf("engines disagree on %q. evaluator=%s, vm=%s", input, want, got)
+		}
+	}
+}
+
+func TestEvaluationOrder(t *testing.T) {
+	operators := []string{"+", "-", "*", "/", "%", "**", "&", "|", "^", "<<", ">>", "<", ">", "<=", ">=", "==", "!=", "&&", "||", "??"}
+
+	tests := []vmTestCase{}
+	for _, op := range operators {
+		// ||는 왼쪽이 거짓이어야, ??는 왼쪽이 null이어야 오른쪽을 평가한다.
+		left := "6"
+		switch op {
+		case "||":
+			left = "false"
+		case "??":
+			left = "null"
+		}
+		tests = append(tests, vmTestCase{
+			fmt.Sprintf(`let s = ""; let l = fn(name, v) { s += name; v }; l("a", %s) %s l("b", 3); s`, left, op),
+			"ab",
+		})
+	}
+	tests = append(tests,
+		vmTestCase{`let s = ""; let l = fn(name, v) { s += name; v }; l("a", 1) + l("b", 2) * l("c", 3); s`, "abc"},
+		vmTestCase{"let x = 1; let f = fn() { x = 10; 1 }; x += f(); x", 2},
+		vmTestCase{`let s = ""; let l = fn(name, v) { s += name; v }; let a = [0]; l("a", a)[l("b", 0)] += l("c", 1); s`, "abc"},
+		// 해시 리터럴의 키와 값은 소스에 나온 순서대로 평가한다.
+		vmTestCase{`let s = ""; let l = fn(name, v) { s += name; v }; {l("z", 1): l("y", 2), l("b", 3): l("a", 4)}; s`, "zyba"},
+		vmTestCase{`{1: "first", 1: "second"}[1]`, "second"},
+	)
+	runVmTests(t, tests)
+}
+
+func bigInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+// int64 범위를 넘으면 큰 정수가 되고, 다시 범위 안으로 들어오면 정수가 된다.
+func TestBigIntegerArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"9223372036854775807 + 1", bigInt("9223372036854775808")},
+		{"-9223372036854775807 - 2", bigInt("-9223372036854775809")},
+		{"9223372036854775807 * 2", bigInt("18446744073709551614")},
+		{"(9223372036854775807 + 1) - 1", 9223372036854775807},
+		{"9223372036854775807 * 2 / 2", 9223372036854775807},
+		{"-9223372036854775807 - 1", -9223372036854775807 - 1},
+		{"-(-9223372036854775807 - 1)", bigInt("9223372036854775808")},
+		{"(-9223372036854775807 - 1) / -1", bigInt("9223372036854775808")},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(25)", bigInt("15511210043330985984000000")},
+		{"9223372036854775807 + 1 > 9223372036854775807", true},
+		{"9223372036854775807 + 1 == 9223372036854775807 + 1", true},
+		{"9223372036854775807 + 1 != 9223372036854775806 + 2", false},
+		{"9223372036854775807 + 1 < 0.5", false},
+		{"(9223372036854775807 + 1) * 0.5", 4611686018427387904.0},
+		{`int("123456789012345678901234567890")`, bigInt("123456789012345678901234567890")},
+		{`{9223372036854775807 + 1: "big"}[9223372036854775807 * 2 / 2 + 1]`, "big"},
+		// int64 범위를 넘는 리터럴
+		{"9223372036854775808", bigInt("9223372036854775808")},
+		{"18446744073709551614", bigInt("18446744073709551614")},
+		{"-9223372036854775808", -9223372036854775807 - 1},
+		{"9223372036854775808 == 2 ** 63", true},
+		{"18446744073709551614 / 2", 9223372036854775807},
+	}
+	runVmTests(t, tests)
+}
+
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+	}
+	return nil
+}
+
 // 아무것도 스택에 넣지 못한 상태에서 뭔가를 꺼내려 했기에, 뭔가를 꺼냐려 했기에, 인덱스 범위 초과로 인해 패닉이 발생
 // Go 언어에서 패닉은 프로그램을 지속할 수 없으면 사용한다. 따라서 패닉이 발생한 즉시 현재 함수의 실행을 종료한다.
 // 그리고 지연함수를 실행하면서 고루틴 스택을 타고 올라간다. 이런 프로세스가 고루틴 스택의 최상단에 도달하면 프로그램이 죽는다.
@@ -209,6 +476,35 @@ func TestBooleanExpression(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+// 문자열, 배열, 해시, null은 값으로 비교한다.
+func TestValueEquality(t *testing.T) {
+	tests := []vmTestCase{
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"a" + "b" == "ab"`, true},
+		{`"a" < "b"`, true},
+		{`"b" > "a"`, true},
+		{`"ab" < "a"`, false},
+		{`"a" <= "a"`, true},
+		{`"a" >= "b"`, false},
+		{`[1, [2, "x"]] == [1, [2, "x"]]`, true},
+		{`[1, 2] == [2, 1]`, false},
+		{`[1] == [1, 1]`, false},
+		{`[1] == [1.0]`, true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`{} == []`, false},
+		{`null == null`, true},
+		{`null == false`, false},
+		{`let a = [1]; a[0] = a; let b = [1]; b[0] = b; a == b`, true},
+		{`let f = fn() { 1 }; f == f`, true},
+		{`fn() { 1 } == fn() { 1 }`, false},
+	}
+	runVmTests(t, tests)
+}
+
 func testBooleanObject(expected bool, actual object.Object) error {
 	result, ok := actual.(*object.Boolean)
 	if !ok {
@@ -248,6 +544,184 @@ func TestConditionals(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+// 대입이 없으므로 반복문은 break, continue, return으로만 빠져나온다.
+func TestLogicalExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && 2", 2},
+		{"0 && 2", 2},
+		{"false && 2", false},
+		{"1 || 2", 1},
+		{"if (false) { 1 } || 3", 3},
+		{`"" || "default"`, ""},
+		{"let x = 0; x != 0 && 10 / x > 1", false},
+		{"let x = 5; x != 0 && 10 / x > 1", true},
+		{"let n = 0; let f = fn() { n += 1; true }; false && f(); true || f(); n", 0},
+		{"let n = 0; let f = fn() { n += 1; false }; f() || f() && f(); n", 2},
+		{"let f = fn(x) { if (x > 0 && x < 10 || x == 42) { 1 } else { 0 } }; [f(5), f(42), f(11), f(-1)]", []int{1, 1, 0, 0}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{`let f = fn() { while (false) { 1 } }; f()`, Null},
+		{`let f = fn() { while (true) { return 5; } }; f()`, 5},
+		{`let f = fn() { while (true) { if (true) { break; } } 7 }; f()`, 7},
+		{`let f = fn(n) { while (true) { if (true) { if (n > 0) { break; } } } n }; f(3)`, 3},
+		{`let f = fn(xs) { while (len(xs) > 0) { return first(xs); } 0 }; [f([]), f([4, 5])]`, []int{0, 4}},
+		{`let f = fn() { let n = 0; while (if (n == 0) { true } else { false }) { break; } 1 }; f()`, 1},
+		{`while (true) { break; } 2`, 2},
+		{`let f = fn() { let once = [true]; while (first(once)) { let once = [false]; break; } 8 }; f()`, 8},
+		{`let f = fn() { while (true) { let g = fn() { 1 }; if (g() == 1) { break; } continue; } 3 }; f()`, 3},
+		// 명령문 자리의 if에서 빠져나가도 스택에 값이 쌓이지 않는다.
+		{`let i = 0; let n = 0; while (i < 5000) { i += 1; if (i % 2 == 0) { continue } else { if (i > 4000) { break } } n += 1 }; n`, 2000},
+	}
+	runVmTests(t, tests)
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{`let f = fn() { for (x in []) { return 1; } 0 }; f()`, 0},
+		{`let f = fn(xs) { for (i, x in xs) { if (x > 2) { return i; } } -1 }; [f([1, 2, 3, 4]), f([1])]`, []int{2, -1}},
+		{`let f = fn() { for (i, ch in "héllo") { if (i == 1) { return ch; } } }; f()`, "é"},
+		{`let f = fn() { for (k, v in {"a": 1}) { return k + "${v}"; } }; f()`, "a1"},
+		{`let f = fn() { for (k in {"a": 1}) { return k; } }; f()`, "a"},
+		// 해시는 키를 넣은 순서대로 순회한다.
+		{`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; let s = ""; for (k, v in h) { s += k + "${v}"; } s`, "b4a2c3"},
+		{`let f = fn() { for (x in range(10, 0, -3)) { if (x < 5) { return x; } } }; f()`, 4},
+		{`let f = fn() { for (x in range(3)) { for (y in range(3)) { if (y == 1) { break; } } if (x == 2) { return x * 10; } } }; f()`, 20},
+		{`let f = fn() { for (x in range(5)) { if (x < 3) { continue; } return x; } }; f()`, 3},
+		{`let f = fn() { for (x in [1, 2, 3]) { } x }; f()`, 3},
+		{`let f = fn(x) { for (x in [x, x + 1]) { } x }; f(7)`, 8},
+		{`for (x in [1, 2]) { break; } 5`, 5},
+		{`for (x in [1, 2]) { } x`, 2},
+		{`len(range(1, 10, 4))`, 3},
+		// 명령문 자리의 if에서 빠져나가도 스택에 값이 쌓이지 않는다.
+		{`let n = 0; for (x in range(5000)) { if (x % 2 == 0) { continue } n += 1 }; n`, 2500},
+	}
+	runVmTests(t, tests)
+}
+
+// 스택 위에 반복자가 없으면 OpIterNext는 멈추지 않고 에러를 돌려준다.
+func TestIterNextWithoutIterator(t *testing.T) {
+	bytecode := &compiler.Bytecode{
+		Instructions: append(code.Make(code.OpConstant, 0), code.Make(code.OpIterNext, 9, 1)...),
+		Constants:    []object.Object{&object.Integer{Value: 1}},
+	}
+	err := New(bytecode).Run()
+	if err == nil || !strings.Contains(err.Error(), "iterator expected on stack, got *object.Integer") {
+		t.Fatalf("wrong error. got=%v", err)
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`let x = 1; x = 2; x`, 2},
+		{`let x = 1; x = x + 1`, 2},
+		{`let a = 1; let b = 2; a = b = 3; a + b`, 6},
+		{`let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x`, 6},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{`let f = fn() { let x = 1; x += 1; x }; f()`, 2},
+		{`let sum = 0; for (x in range(5)) { sum += x; } sum`, 10},
+		{`let f = fn(n) { let i = 0; while (i < n) { i += 1; } i }; f(4)`, 4},
+		{`let f = fn(n) { n = n * 2; n }; f(21)`, 42},
+		{`let x = 1; let f = fn() { x }; let x = 2; f()`, 2},
+		{`let f = fn() { f = 1 }; f(); f`, 1},
+		{`let g = fn() { let f = fn() { f = 1; f }; f() }; g()`, 1},
+		{`let g = fn() { let f = fn() { fn() { f = 2 } }; f()(); f }; g()`, 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestClosureAssignment(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+			let newCounter = fn() { let count = 0; fn() { count += 1 } };
+			let counter = newCounter();
+			counter(); counter(); counter();
+			`,
+			expected: 3,
+		},
+		{
+			// 카운터마다 자기만의 count를 가진다.
+			input: `
+			let newCounter = fn() { let count = 0; fn() { count += 1 } };
+			let a = newCounter(); let b = newCounter();
+			a(); a(); b();
+			[a(), b()]
+			`,
+			expected: []int{3, 2},
+		},
+		{
+			// 같은 변수를 붙잡은 두 클로저와 바깥 함수가 값을 공유한다.
+			input: `
+			let pair = fn() {
+				let n = 0;
+				let inc = fn() { n += 1 };
+				let get = fn() { n };
+				inc(); inc();
+				[get(), n]
+			};
+			pair()
+			`,
+			expected: []int{2, 2},
+		},
+		{
+			// 바깥 함수가 클로저를 만든 뒤에 대입해도 클로저가 본다.
+			input: `
+			let f = fn() { let x = 1; let g = fn() { x }; x = 5; g() };
+			f()
+			`,
+			expected: 5,
+		},
+		{
+			// 두 단계 위 함수의 변수도 같은 셀을 공유한다.
+			input: `
+			let f = fn() {
+				let x = 0;
+				let g = fn() { fn() { x += 10 } };
+				g()(); g()();
+				x
+			};
+			f()
+			`,
+			expected: 20,
+		},
+		{
+			// 이전 호출이 남긴 셀을 새 호출이 건드리지 않는다.
+			input: `
+			let make = fn() { let v = 1; fn() { v } };
+			let g = make();
+			let other = fn() { let w = 99; w };
+			other();
+			g()
+			`,
+			expected: 1,
+		},
+		{
+			// 반복문 안에서 만든 클로저는 같은 바인딩을 공유한다.
+			input: `
+			let f = fn() {
+				let fns = [];
+				for (i in range(3)) { let j = i; fns = push(fns, fn() { j }); }
+				let total = 0;
+				for (g in fns) { total += g(); }
+				total
+			};
+			f()
+			`,
+			expected: 6,
+		},
+	}
+	runVmTests(t, tests)
+}
+
 func TestGlobalLetStatements(t *testing.T) {
 	tests := []vmTestCase{
 		{"let one = 1; one", 1},
@@ -260,9 +734,18 @@ func TestGlobalLetStatements(t *testing.T) {
 
 func TestStringExpression(t *testing.T) {
 	tests := []vmTestCase{
-		{"monkey", "monkey"},
-		{"mon" + "key", "monkey"},
-		{"mon" + "key" + "banana", "monkeybanana"},
+		{`"monkey"`, "monkey"},
+		{`"mon" + "key"`, "monkey"},
+		{`"mon" + "key" + "banana"`, "monkeybanana"},
+		{`"a\tb\n\"c\"\\"`, "a\tb\n\"c\"\\"},
+		{`"\u{D55C}\u{AE00}"`, "한글"},
+		{`let name = "Monkey"; let age = 3; "Hello ${name}, you are ${age + 1}"`, "Hello Monkey, you are 4"},
+		{`"${[1, true]} ${fn(x) { x }(5)} ${"in" + "ner"}"`, "[1, true] 5 inner"},
+		// 배열과 해시 안의 문자열은 따옴표로 감싸고, 함수는 이름과 파라미터를 보여준다.
+		{`"${["a, b", {"k": "v"}]}"`, `["a, b", {"k": "v"}]`},
+		{`let fib = fn(n) { n }; "${fib} ${fn(x, y) { x }} ${len}"`, "<fn fib(n)> <fn(x, y)> <builtin len>"},
+		{`"${"a${1}"}b"`, "a1b"},
+		{`"\${x}"`, "${x}"},
 	}
 	runVmTests(t, tests)
 
@@ -307,6 +790,48 @@ func TestHashLiteral(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+// 모든 문자열이 같은 해시 값을 갖게 해서 충돌한 키를 실제 값으로 구분하는지 확인한다.
+// big과 big + 1은 실수로 바꾸면 같은 값이라서 원래 같은 해시 값을 갖는다.
+func TestHashCollisions(t *testing.T) {
+	hashBytes := object.HashBytes
+	object.HashBytes = func([]byte) uint64 { return 0 }
+	defer func() { object.HashBytes = hashBytes }()
+
+	tests := []vmTestCase{
+		{`{"a": 1, "b": 2}["a"]`, 1},
+		{`{"a": 1, "b": 2}["b"]`, 2},
+		{`{"a": 1, "b": 2}["c"]`, Null},
+		{`let h = {"a": 1}; h["b"] = 2; h["a"] = 3; [h["a"], h["b"]]`, []int{3, 2}},
+		{`let big = 9223372036854775807 + 1; let h = {big: 1, big + 1: 2}; h[big + 1]`, 2},
+		{`let s = ""; for (k, v in {"a": 1, "b": 2}) { s += k + "${v}"; } s`, "a1b2"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestCompoundHashKeys(t *testing.T) {
+	tests := []vmTestCase{
+		{`let visited = {}; visited[[1, 2]] = 5; visited[[1, 2]]`, 5},
+		{`{[1, [2, "a"]]: 5}[[1, [2, "a"]]]`, 5},
+		{`{[1, 2]: 5}[[2, 1]]`, Null},
+		{`{[]: 5}[[]]`, 5},
+		{`{null: 5}[null]`, 5},
+		{`let h = {[1]: 1}; h[[1]] = 2; let n = 0; for (k in h) { n += 1; } n * 10 + h[[1]]`, 12},
+		// 키로 넣은 배열을 나중에 바꿔도 해시에는 넣을 때의 값이 키로 남는다.
+		{`let k = [1, 2]; let h = {}; h[k] = 5; k[0] = 9; h[[1, 2]]`, 5},
+		{`let k = [1, 2]; let h = {}; h[k] = 5; k[0] = 9; h[k]`, Null},
+		// 숫자 키는 == 처럼 타입과 상관없이 값으로 찾는다.
+		{`{1: 5}[1.0]`, 5},
+		{`{1.0: 5}[1]`, 5},
+		{`{-0.0: 5}[0]`, 5},
+		{`{[1, [2]]: 5}[[1.0, [2.0]]]`, 5},
+		{`{[1.0]: 5}[[1]]`, 5},
+		{`let big = 9223372036854775807 + 1; {big: 5}[2.0 ** 63]`, 5},
+		{`{1.5: 5}[1]`, Null},
+		{`let h = {1: 1}; h[1.0] = 2; let n = 0; for (k in h) { n += 1; } n * 10 + h[1]`, 12},
+	}
+	runVmTests(t, tests)
+}
+
 func TestIndexExpression(t *testing.T) {
 	tests := []vmTestCase{
 		{"[1,2,3][1]", 2},
@@ -324,6 +849,22 @@ func TestIndexExpression(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestIndexAssignExpression(t *testing.T) {
+	tests := []vmTestCase{
+		{"let a = [1, 2, 3]; a[1] = 5; a", []int{1, 5, 3}},
+		{"let a = [1, 2, 3]; a[2] = 7", 7},
+		{"let a = [1, 2]; let b = a; b[0] = 9; a", []int{9, 2}},
+		{"let a = [[1], [2]]; a[1][0] += 10; a[1][0]", 12},
+		{"let h = {\"k\": 1}; h[\"k\"] = 2; h[\"k\"]", 2},
+		{"let h = {}; h[1] = 5; h[1] *= 3; h[1]", 15},
+		{"let set = fn(xs, i, v) { xs[i] = v }; let a = [0, 0]; set(a, 1, 4); a", []int{0, 4}},
+		{"let a = [0, 0, 0]; for (i, x in a) { a[i] = i * 2; } a", []int{0, 2, 4}},
+		{"let i = 0; let next = fn() { i += 1; i - 1 }; let a = [10, 20]; a[next()] += 1; [i, a[0], a[1]]", []int{1, 11, 20}},
+		{"let a = [1]; a[0] = a; len(a)", 1},
+	}
+	runVmTests(t, tests)
+}
+
 func TestCallingFunctionsWithoutArguments(t *testing.T) {
 	tests := []vmTestCase{
 		{input: `let fivePlusTen = fn() { 5 + 10; }; fivePlusTen();`, expected: 15},
@@ -341,18 +882,33 @@ func TestFunctionsWithReturnStatement(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+// 최상위의 return은 평가기처럼 그 값으로 프로그램을 끝낸다.
+func TestTopLevelReturn(t *testing.T) {
+	tests := []vmTestCase{
+		{input: "return 5", expected: 5},
+		{input: "return 5; 10", expected: 5},
+		{input: "1\nreturn\n2", expected: Null},
+		{input: "if (true) { return 3 } 4", expected: 3},
+		{input: "let i = 0; while (true) { i += 1; if (i == 3) { return i * 10 } }", expected: 30},
+		{input: "let f = fn() { return 1 }; return f() + 1; 100", expected: 2},
+	}
+	runVmTests(t, tests)
+}
+
 func TestFunctionsWithoutReturnStatement(t *testing.T) {
 	tests := []vmTestCase{
 		{input: `let noReturn = fn() {  }; noReturn();`, expected: Null},
-		{input: `let noReturn = fn() {  };  let noReturn2 = fn() { noReturn(); }; noReturn(); noReturn2();`, expected: Null},
+		{input: `let noReturn = fn() {  };  let noReturnTwo = fn() { noReturn(); }; noReturn(); noReturnTwo();`, expected: Null},
+		{input: "let bare = fn() { return\n 10 }; bare();", expected: Null},
+		{input: "let bare = fn(x) { if (x) { return }\n x }; bare(true);", expected: Null},
 	}
 	runVmTests(t, tests)
 }
 
 func TestFirstClassFunctions(t *testing.T) {
 	tests := []vmTestCase{
-		{input: `let returnsOne = fn() {1;}; let returnsTwo =fn { returnsOne; } ; returnsTwo()();`, expected: 1},
-		{input: `let returnsOneReturner = fn() { returnsOne = fn(){1;}; returnsOne } ; returnsOneReturner()();`, expected: 1},
+		{input: `let returnsOne = fn() {1;}; let returnsOneReturner = fn() { returnsOne; }; returnsOneReturner()();`, expected: 1},
+		{input: `let returnsOneReturner = fn() { let returnsOne = fn(){1;}; returnsOne } ; returnsOneReturner()();`, expected: 1},
 	}
 	runVmTests(t, tests)
 }
@@ -367,7 +923,7 @@ func TestCallingFunctionsWithBinding(t *testing.T) {
 		{input: `let one = fn() { let one = 1; one }; one();`, expected: 1},
 		{input: `let oneAndTwo = fn() { let one = 1; let two = 2; one + two; }; oneAndTwo();`, expected: 3},
 		{input: `let oneAndTwo = fn() { let one = 1; let two = 2; one + two; }; let threeAndFour = fn() { let three = 3; let four = 4; three + four; } oneAndTwo() + threeAndFour();`, expected: 10},
-		{input: `let firstFoobar = fn() { let foobar = 50; one }; let secondFoobar = fn() { let foobar = 100; one }; firstFoobar()+secondFoobar();`, expected: 150},
+		{input: `let firstFoobar = fn() { let foobar = 50; foobar }; let secondFoobar = fn() { let foobar = 100; foobar }; firstFoobar()+secondFoobar();`, expected: 150},
 		{input: `let globalSeed = 50; let minusOne = fn(){let num=1; globalSeed-num;} let minusTwo = fn(){let num=2; globalSeed-num;} minusTwo()+minusOne();`,
 			expected: 97},
 	}
@@ -386,9 +942,10 @@ func TestCallingFunctionsWithArgumentsAndBinding(t *testing.T) {
 
 func TestCallingFunctionsWithWrongArguments(t *testing.T) {
 	tests := []vmTestCase{
-		{input: `fn(){1;}(1);`, expected: `wrong number of arguments: want=0, got=1`},
-		{input: `fn(a){a;}();`, expected: `wrong number of arguments: want=1, got=0`},
-		{input: `fn(a, b){a+b;}(1);`, expected: `wrong number of arguments: want=2, got=1`},
+		{input: `fn(){1;}(1);`, expected: `1:9: wrong number of arguments to fn() defined at 1:1-1:8: want=0, got=1`},
+		{input: `fn(a){a;}();`, expected: `1:10: wrong number of arguments to fn(a) defined at 1:1-1:9: want=1, got=0`},
+		{input: `fn(a, b){a+b;}(1);`, expected: `1:15: wrong number of arguments to fn(a, b) defined at 1:1-1:14: want=2, got=1`},
+		{input: "let fib = fn(n) {\n  n\n};\nfib(1, 2)", expected: `4:4: wrong number of arguments to fib(n) defined at 1:11-3:1: want=1, got=2`},
 	}
 	for _, tt := range tests {
 		program := parse(tt.input)
@@ -419,16 +976,93 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`puts("hello", "world!")`, Null},
 		{`first([1,2,3])`, 1},
 		{`len(1)`, &object.Error{Message: "argument to 'len' not supported, got INTEGER"}},
-		{`len("one", "tow")`, &object.Error{Message: "wrong number of arguments, got 2, want=1"}},
+		{`len("one", "tow")`, &object.Error{Message: "wrong number of arguments. got=2, want=1"}},
 		{`first([])`, Null},
-		{`first(1)`, &object.Error{Message: "argument to 'first' must be ARRAY, got INTEGER"}},
+		{`first(1)`, &object.Error{Message: "argument to 'first' must be ARRAY or STRING, got INTEGER"}},
 		{`last([1,2,3])`, 3},
 		{`last([])`, Null},
-		{`last(1)`, &object.Error{Message: "argument to 'last' must be ARRAY, got INTEGER"}},
+		{`last(1)`, &object.Error{Message: "argument to 'last' must be ARRAY or STRING, got INTEGER"}},
 		{`rest([1,2,3])`, []int{2, 3}},
 		{`rest([])`, Null},
 		{`push([],1)`, []int{1}},
 		{`push(1,1)`, &object.Error{Message: "argument to 'push' must be ARRAY, got INTEGER"}},
+		// keys와 values는 키를 넣은 순서대로 돌려준다.
+		{`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; let s = ""; for (k in keys(h)) { s += k; } s`, "bac"},
+		{`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; values(h)`, []int{4, 2, 3}},
+		{`keys({})`, []int{}},
+		{`values({})`, []int{}},
+		{`keys([1])`, &object.Error{Message: "argument to 'keys' must be HASH, got ARRAY"}},
+		{`values({}, {})`, &object.Error{Message: "wrong number of arguments. got=2, want=1"}},
+	}
+	runVmTests(t, tests)
+}
+
+// 문자열의 길이와 인덱스는 바이트가 아니라 문자 단위
+func TestUnicodeStrings(t *testing.T) {
+	tests := []vmTestCase{
+		{`len("한글")`, 2},
+		{`len("héllo")`, 5},
+		{`let 이름 = "철수"; 이름`, "철수"},
+		{`"한글"[0]`, "한"},
+		{`"한글"[1]`, "글"},
+		{`"한글"[2]`, Null},
+		{`"한글"[-1]`, Null},
+		{`first("한글")`, "한"},
+		{`last("한글")`, "글"},
+		{`rest("한글")`, "글"},
+		{`rest("")`, Null},
+		{`first("")`, Null},
+		{`let 길이 = fn(s) { if (len(s) == 0) { 0 } else { 1 + 길이(rest(s)) } }; 길이("가나다")`, 3},
 	}
 	runVmTests(t, tests)
 }
+
+// 실행 중 에러는 에러를 일으킨 명령어의 소스 위치를 담고 있어야 한다.
+func TestRuntimeErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + true", "1:3: unsupported types for binary operation: INTEGER BOOLEAN"},
+		{"let f = fn() {\n  -true\n};\nf();", "2:3: unsupported type for negation: BOOLEAN"},
+		{"let x = 0;\n[1][x] + \"a\"", "2:8: unsupported types for binary operation: INTEGER STRING"},
+		{"10 / (1 - 1)", "1:4: division by zero"},
+		{"10 % 0", "1:4: division by zero"},
+		{"1 << -1", "1:3: negative shift count: -1"},
+		{"1.5 & 1", "1:5: unsupported types for binary operation: FLOAT INTEGER"},
+		{"2 ** 100000000", "1:3: integer result too large"},
+		{"(9223372036854775807 + 1) / 0", "1:27: division by zero"},
+		{"for (x in 5) { }", "1:1: cannot iterate over INTEGER"},
+		{"for (i in range(0, 10, 0)) { }", "1:1: range step must not be zero"},
+		// 깊은 재귀는 지역 변수가 있어도 멈추지 않고 스택 넘침 에러가 된다.
+		{"let f = fn(n) { let a = 1; let b = 2; let c = 3; let d = 4; let e = 5; f(n + 1) }; f(0)", "1:73: stack overflow"},
+		{"let f = fn() { f() }; f()", "1:17: stack overflow"},
+		{"let x = 1;\nx /= 0", "2:3: division by zero"},
+		{"let a = [1];\na[1] = 2", "2:6: index out of range: 1 (length 1)"},
+		{"let a = [1];\na[\"x\"] = 2", "2:8: array index must be INTEGER, got STRING"},
+		{"let s = \"ab\";\ns[0] = \"c\"", "2:6: index assignment not supported: STRING"},
+		{"let h = {};\nh[fn() { 1 }] = 2", "2:15: unusable as hash key: CLOSURE"},
+		{"{[1, fn() { 1 }]: 2}", "1:1: unusable as hash key: ARRAY"},
+		{"let a = [1];\na[0] = a;\n{a: 1}", "3:1: unusable as hash key: ARRAY"},
+		{"let h = {};\nh[0.0 / 0.0] = 1", "2:14: unusable as hash key: FLOAT"},
+		{"{[0.0 / 0.0]: 1}", "1:1: unusable as hash key: ARRAY"},
+		{"for (k in {[1]: 1}) {\n  k[0] = 2\n}", "2:8: cannot modify array used as hash key"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error but resulted in none.")
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error: want=%q, got=%q", tt.expected, err)
+		}
+	}
+}